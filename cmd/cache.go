@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/niedch/mux-session/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cache of directories shown in the picker",
+	Long: `The picker shows the directories found by its last scan right away and
+scans the search paths again in the background when any of them changed. The
+cache is kept in XDG_CACHE_HOME/mux-session/items.json.`,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove the cached directories",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if verbose {
+			logger.SetEnabled(true)
+		}
+
+		cache, err := dataproviders.DefaultItemCache()
+		if err != nil {
+			logger.Fatalf("Failed to open item cache: %v\n", err)
+		}
+
+		if err := cache.Clear(); err != nil {
+			logger.Fatalf("Failed to clear item cache: %v\n", err)
+		}
+		fmt.Printf("Cleared %s\n", cache.Path())
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -1,11 +1,17 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/niedch/mux-session/internal/conf"
+	"github.com/niedch/mux-session/internal/dataproviders"
 	"github.com/niedch/mux-session/internal/logger"
+	"github.com/niedch/mux-session/internal/tmux"
 	"github.com/spf13/cobra"
 )
 
+var explainId string
+
 // configValidateCmd represents the configValidate command
 var configValidateCmd = &cobra.Command{
 	Use:   "config-validate",
@@ -13,7 +19,10 @@ var configValidateCmd = &cobra.Command{
 	Long: `Loads the mux-session configuration file and displays it in a formatted
 JSON structure. This command validates that your configuration is properly
 parsed and shows the current settings including search paths and project
-configurations.`,
+configurations.
+
+With --explain <id> it prints which project config applies to the item with
+that ID and why, followed by the resolved project config.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if verbose {
 			logger.SetEnabled(true)
@@ -25,10 +34,67 @@ configurations.`,
 		}
 		logger.Printf("Configuration loaded successfully\n")
 
+		if explainId != "" {
+			explainProjectConfig(config, explainId)
+			return
+		}
+
 		config.PrettyPrint()
 	},
 }
 
+func explainProjectConfig(config *conf.Config, id string) {
+	tmuxWrapper, err := tmux.NewTmux(socket)
+	if err != nil {
+		logger.Fatalf("Failed to initialize tmux: %v\n", err)
+	}
+
+	directoryProvider := dataproviders.NewDirectoryProvider(config.SearchPaths)
+	tmuxProvider := dataproviders.NewTmuxProvider(tmuxWrapper)
+	items, err := dataproviders.NewDeduplicatorProvider(directoryProvider, tmuxProvider).GetItems()
+	if err != nil {
+		logger.Fatalf("Failed to get items: %v\n", err)
+	}
+
+	item, err := findItem(id, items)
+	if err != nil {
+		logger.Fatalf("Failed to find item: %v\n", err)
+	}
+
+	fmt.Println(config.MatchProject(item).Reason)
+
+	projectConfig, err := config.GetProjectConfig(item)
+	if err != nil {
+		logger.Fatalf("Failed to get project config: %v\n", err)
+	}
+
+	repo, err := findRepoConfig(item)
+	if err != nil {
+		logger.Fatalf("Failed to load repo config: %v\n", err)
+	}
+	if repo != nil {
+		store, err := conf.DefaultTrustStore()
+		if err != nil {
+			logger.Fatalf("Failed to open trust store: %v\n", err)
+		}
+
+		trusted, err := store.IsTrusted(repo)
+		if err != nil {
+			logger.Fatalf("Failed to read trust store: %v\n", err)
+		}
+
+		if trusted {
+			fmt.Printf("repo config %s is trusted and merged over it\n", repo.File)
+			projectConfig = conf.MergeRepoConfig(projectConfig, repo)
+		} else {
+			fmt.Printf("repo config %s is not trusted and ignored\n", repo.File)
+		}
+	}
+
+	projectConfig.PrettyPrint()
+}
+
 func init() {
+	configValidateCmd.Flags().StringVar(&explainId, "explain", "", "Explain which project config applies to the item with this ID")
 	rootCmd.AddCommand(configValidateCmd)
 }
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/niedch/mux-session/internal/history"
+	"github.com/niedch/mux-session/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var historyLimit int
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the sessions recently opened through mux-session",
+	Long:  `Lists the sessions created or switched to through mux-session, the most recent first.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if verbose {
+			logger.SetEnabled(true)
+		}
+
+		store, err := history.DefaultStore()
+		if err != nil {
+			logger.Fatalf("Failed to open history: %v\n", err)
+		}
+
+		entries, err := store.Entries()
+		if err != nil {
+			logger.Fatalf("Failed to read history: %v\n", err)
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No sessions in the history")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for i := len(entries) - 1; i >= 0; i-- {
+			if historyLimit > 0 && len(entries)-i > historyLimit {
+				break
+			}
+			entry := entries[i]
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Time.Format("2006-01-02 15:04:05"), entry.Action, entry.Id, entry.Path)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Number of entries to list, 0 lists all")
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/niedch/mux-session/internal/conf"
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/niedch/mux-session/internal/history"
+	"github.com/niedch/mux-session/internal/logger"
+	"github.com/niedch/mux-session/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+var lastCmd = &cobra.Command{
+	Use:   "last",
+	Short: "Switch to the previous session opened through mux-session",
+	Long: `Switches to the most recent session opened through mux-session other than
+the current one, so that binding it to a key flips between the last two
+projects. Unlike tmux's switch-client -l, sessions not opened through
+mux-session are skipped, as are projects whose directory is gone. A session
+which was killed since is created again from its project config.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if verbose {
+			logger.SetEnabled(true)
+		}
+		logger.Printf("Loading configuration from: %s\n", configFile)
+		config, err := conf.Load(configFile)
+		if err != nil {
+			logger.Fatalf("Failed to load config: %v\n", err)
+		}
+
+		store, err := history.DefaultStore()
+		if err != nil {
+			logger.Fatalf("Failed to open history: %v\n", err)
+		}
+
+		entries, err := store.Entries()
+		if err != nil {
+			logger.Fatalf("Failed to read history: %v\n", err)
+		}
+
+		controlTmux, err := tmux.NewControlTmux(socket)
+		if err != nil {
+			logger.Fatalf("Failed to initialize tmux: %v\n", err)
+		}
+		defer controlTmux.Close()
+
+		// The newest entry is not necessarily the current session, which may
+		// have been switched to without mux-session
+		current := ""
+		if tmux.InsideTmux() {
+			if current, err = controlTmux.CurrentSession(); err != nil {
+				logger.Printf("Failed to get the current session: %v\n", err)
+			}
+		}
+
+		previous, ok := history.Previous(entries, current)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "No previous session in the history")
+			controlTmux.Close()
+			os.Exit(1)
+		}
+		logger.Printf("Previous session: id=%s, path=%s\n", previous.Id, previous.Path)
+
+		item := &dataproviders.Item{
+			Id:         previous.Id,
+			Display:    previous.Path,
+			Path:       previous.Path,
+			ParentId:   previous.ParentId,
+			ParentPath: previous.ParentPath,
+		}
+		openSession(newOrchestrator(controlTmux, config), config, item)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lastCmd)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/niedch/mux-session/internal/dataproviders"
 	"github.com/niedch/mux-session/internal/fzf"
 	"github.com/niedch/mux-session/internal/logger"
+	"github.com/niedch/mux-session/internal/pins"
 	"github.com/niedch/mux-session/internal/tmux"
 	"github.com/niedch/mux-session/internal/tree"
 	"github.com/spf13/cobra"
@@ -37,7 +38,8 @@ var listSessionsCmd = &cobra.Command{
 
 		directoryProvider := dataproviders.NewDirectoryProvider(config.SearchPaths)
 		tmuxProvider := dataproviders.NewTmuxProvider(tmuxWrapper)
-		composedProvider := dataproviders.NewDeduplicatorProvider(directoryProvider, tmuxProvider).WithMarkDuplicates(true)
+		deduplicatedProvider := dataproviders.NewDeduplicatorProvider(directoryProvider, tmuxProvider).WithMarkDuplicates(true)
+		composedProvider := dataproviders.NewTagProvider(deduplicatedProvider, config.Tags)
 
 		logger.Printf("Getting items from providers\n")
 		items, err := composedProvider.GetItems()
@@ -51,20 +53,47 @@ var listSessionsCmd = &cobra.Command{
 			return
 		}
 
+		sorter, err := fzf.NewSorter(config)
+		if err != nil {
+			logger.Fatalf("Failed to sort items: %v\n", err)
+		}
+		sorter(items)
+
 		if search != "" {
-			items = fzf.FilterTree(items, search)
+			items = fzf.FilterTree(items, search, fzf.NewField(config))
 			logger.Printf("Filtered to %d items\n", len(items))
 		}
 
+		pinStore, err := pins.DefaultStore()
+		if err != nil {
+			logger.Fatalf("Failed to open pins: %v\n", err)
+		}
+		pinned, err := pinStore.Set()
+		if err != nil {
+			logger.Fatalf("Failed to read pins: %v\n", err)
+		}
+		items, pinnedItems := fzf.GroupPinned(items, pinned)
+
 		flattenedItems := tree.FlattenItems(items)
 		logger.Printf("Displaying %d items\n", len(flattenedItems))
 		for _, item := range flattenedItems {
 			fmt.Println(item.Display)
 		}
+
+		if len(pinnedItems) > 0 {
+			fmt.Println(fzf.PinnedHeader)
+			for _, item := range tree.FlattenItems(pinnedItems) {
+				if pinned[item.Id] {
+					fmt.Println(dataproviders.PINNED_ICON + " " + item.Display)
+					continue
+				}
+				fmt.Println(item.Display)
+			}
+		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(listSessionsCmd)
-	listSessionsCmd.Flags().StringVarP(&search, "search", "s", "", "Filter items by search query")
+	listSessionsCmd.Flags().StringVarP(&search, "search", "s", "", "Filter items by search query, key:value filters on metadata like branch, lang, tag and session")
 }
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/niedch/mux-session/internal/logger"
+	"github.com/niedch/mux-session/internal/pins"
+	"github.com/spf13/cobra"
+)
+
+var pinCmd = &cobra.Command{
+	Use:   "pin <id>",
+	Short: "Pin a project to the bottom of the picker",
+	Long: `Pins the project with the given ID, so that it is always listed in the
+Pinned group at the bottom of the picker, next to the cursor. Pins can also be
+toggled in the picker with ctrl+s.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openPinStore()
+		if err := store.Pin(args[0]); err != nil {
+			logger.Fatalf("Failed to pin %s: %v\n", args[0], err)
+		}
+		fmt.Printf("Pinned %s\n", args[0])
+	},
+}
+
+var unpinCmd = &cobra.Command{
+	Use:   "unpin <id>",
+	Short: "Remove a project from the pinned ones",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openPinStore()
+		if err := store.Unpin(args[0]); err != nil {
+			logger.Fatalf("Failed to unpin %s: %v\n", args[0], err)
+		}
+		fmt.Printf("Unpinned %s\n", args[0])
+	},
+}
+
+func openPinStore() *pins.Store {
+	if verbose {
+		logger.SetEnabled(true)
+	}
+
+	store, err := pins.DefaultStore()
+	if err != nil {
+		logger.Fatalf("Failed to open pins: %v\n", err)
+	}
+	return store
+}
+
+func init() {
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(unpinCmd)
+}
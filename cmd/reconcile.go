@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/niedch/mux-session/internal/conf"
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/niedch/mux-session/internal/logger"
+	"github.com/niedch/mux-session/internal/tmux"
+	"github.com/spf13/cobra"
+)
+
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile <id>",
+	Short: "Create windows missing in a running session from its project config",
+	Long: `Compares the windows of a running tmux session with its project configuration.
+Windows which are configured but missing in the session are created together
+with their panels and commands. Windows of the session which are not part of
+the configuration are reported but never killed.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if verbose {
+			logger.SetEnabled(true)
+		}
+		logger.Printf("Loading configuration from: %s\n", configFile)
+		config, err := conf.Load(configFile)
+		if err != nil {
+			logger.Fatalf("Failed to load config: %v\n", err)
+		}
+		logger.Printf("Configuration loaded successfully\n")
+
+		logger.Printf("Initializing tmux wrapper (socket: %s)\n", socket)
+		tmux, err := tmux.NewControlTmux(socket)
+		if err != nil {
+			logger.Fatalf("Failed to initialize tmux: %v\n", err)
+		}
+		defer tmux.Close()
+
+		directoryProvider := dataproviders.NewDirectoryProvider(config.SearchPaths)
+		tmuxProvider := dataproviders.NewTmuxProvider(tmux)
+		composedProvider := dataproviders.NewDeduplicatorProvider(directoryProvider, tmuxProvider).WithMarkDuplicates(true)
+
+		logger.Printf("Getting items from providers\n")
+		items, err := composedProvider.GetItems()
+		if err != nil {
+			logger.Fatalf("Failed to get items: %v\n", err)
+		}
+
+		item, err := findItem(args[0], items)
+		if err != nil {
+			logger.Fatalf("Failed to find item: %v\n", err)
+		}
+		logger.Printf("Found item: id=%s, display=%s\n", item.Id, item.Display)
+
+		projectConfig := getProjectConfig(config, item)
+
+		multiService := newOrchestrator(tmux, config)
+		result, err := multiService.ReconcileSession(item, projectConfig)
+		if err != nil {
+			logger.Fatalf("Failed to reconcile session: %v\n", err)
+		}
+
+		for _, window := range result.Created {
+			fmt.Printf("Created window %s\n", window)
+		}
+		for _, window := range result.Extra {
+			fmt.Printf("Window %s is not in the project config\n", window)
+		}
+		if len(result.Created) == 0 && len(result.Extra) == 0 {
+			fmt.Println("Session matches its project config")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+}
@@ -1,21 +1,23 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/niedch/mux-session/internal/conf"
 	"github.com/niedch/mux-session/internal/dataproviders"
 	"github.com/niedch/mux-session/internal/fzf"
 	"github.com/niedch/mux-session/internal/logger"
-	"github.com/niedch/mux-session/internal/orchestrator"
 	"github.com/niedch/mux-session/internal/tmux"
 	"github.com/spf13/cobra"
 )
 
 var (
-	configFile string
-	socket     string
-	verbose    bool
+	configFile  string
+	socket      string
+	verbose     bool
+	keepPartial bool
+	noCache     bool
 )
 
 var rootCmd = &cobra.Command{
@@ -39,46 +41,63 @@ directory name as the session name.`,
 		logger.Printf("Configuration loaded successfully\n")
 
 		logger.Printf("Initializing tmux wrapper (socket: %s)\n", socket)
-		tmux, err := tmux.NewTmux(socket)
+		tmuxWrapper, err := tmux.NewTmux(socket)
 		if err != nil {
 			logger.Fatalf("Failed to initialize tmux: %v\n", err)
 		}
 
-		multiService := orchestrator.New(tmux)
-
 		directoryProvider := dataproviders.NewDirectoryProvider(config.SearchPaths)
-		tmuxProvider := dataproviders.NewTmuxProvider(tmux)
+		var itemProvider dataproviders.DataProvider = directoryProvider
+		if !noCache {
+			cache, err := dataproviders.DefaultItemCache()
+			if err != nil {
+				logger.Printf("Item cache disabled: %v\n", err)
+			} else {
+				itemProvider = dataproviders.NewCachedProvider(directoryProvider, cache)
+			}
+		}
+		tmuxProvider := dataproviders.NewTmuxProvider(tmuxWrapper)
+		composedProvider := dataproviders.NewDeduplicatorProvider(itemProvider, tmuxProvider).WithMarkDuplicates(true)
+
+		currentSession := ""
+		if tmux.InsideTmux() {
+			if currentSession, err = tmuxWrapper.CurrentSession(); err != nil {
+				logger.Printf("Failed to get the current session: %v\n", err)
+			}
+		}
 
 		logger.Printf("Starting interactive session selector\n")
-		selected, err := fzf.Run(dataproviders.NewDeduplicatorProvider(directoryProvider, tmuxProvider).WithMarkDuplicates(true), config)
+		result, err := fzf.Run(dataproviders.NewTagProvider(composedProvider, config.Tags), tmuxWrapper, currentSession, config)
 
 		if err != nil {
 			logger.Fatalf("Session selector failed: %v\n", err)
 		}
 
-		if selected == nil {
+		if result == nil {
 			logger.Printf("No selection made, exiting\n")
 			return
 		}
-		logger.Printf("Selected session: id=%s, display=%s\n", selected.Id, selected.Display)
-
-		projectConfig := config.GetProjectConfig(selected)
-		logger.Printf("Switching to session: %s\n", selected.Id)
-		ok, err := multiService.SwitchSession(selected)
-		if err != nil {
-			logger.Fatalf("Failed to switch session: %v\n", err)
+		for _, item := range result.Items {
+			logger.Printf("Selected: id=%s, display=%s\n", item.Id, item.Display)
 		}
 
-		if ok {
-			logger.Printf("Switched to existing session: %s\n", selected.Id)
+		if result.Action == fzf.ResultPrint {
+			for _, item := range result.Items {
+				fmt.Println(item.Id)
+			}
 			return
 		}
 
-		logger.Printf("Creating new session: %s\n", selected.Id)
-		if err := multiService.CreateSession(selected, projectConfig); err != nil {
-			logger.Fatalf("Failed to create session: %v\n", err)
+		controlTmux, err := tmux.NewControlTmux(socket)
+		if err != nil {
+			logger.Fatalf("Failed to initialize tmux: %v\n", err)
+		}
+		defer controlTmux.Close()
+
+		if err := openSessions(newOrchestrator(controlTmux, config), config, result.Items); err != nil {
+			controlTmux.Close()
+			os.Exit(1)
 		}
-		logger.Printf("Session created successfully: %s\n", selected.Id)
 	},
 }
 
@@ -97,8 +116,10 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&configFile, "file", "f", "", "Path to config file (default is XDG_CONFIG/mux-session/config.toml)")
 	rootCmd.PersistentFlags().StringVarP(&socket, "socket", "L", "", "tmux socket name for targeting a specific server")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+	rootCmd.PersistentFlags().BoolVar(&keepPartial, "keep-partial", false, "Keep partially created sessions for debugging instead of rolling them back")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "Scan the search paths instead of showing cached items first")
 	rootCmd.Flags().BoolP("toggle", "t", false, "Toggle flag for testing")
 }
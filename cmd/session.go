@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/niedch/mux-session/internal/conf"
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/niedch/mux-session/internal/history"
+	"github.com/niedch/mux-session/internal/logger"
+	"github.com/niedch/mux-session/internal/orchestrator"
+	"github.com/niedch/mux-session/internal/tmux"
+	"golang.org/x/term"
+)
+
+func newOrchestrator(multiplexer tmux.Multiplexer, config *conf.Config) *orchestrator.OrchestratorService {
+	return orchestrator.New(multiplexer).
+		WithKeepPartial(keepPartial).
+		WithReconcileOnSwitch(config.ReconcileOnSwitch)
+}
+
+// openSession switches to the session of the item or creates it from its
+// project config
+func openSession(multiService *orchestrator.OrchestratorService, config *conf.Config, item *dataproviders.Item) {
+	if err := switchOrCreate(multiService, item, getProjectConfig(config, item)); err != nil {
+		logger.Fatalf("%v\n", err)
+	}
+}
+
+// switchOrCreate switches to the running session of the item, or creates it
+// and switches to it
+func switchOrCreate(multiService *orchestrator.OrchestratorService, item *dataproviders.Item, projectConfig conf.ProjectConfig) error {
+	logger.Printf("Switching to session: %s\n", item.Id)
+	ok, err := multiService.SwitchSession(item, projectConfig)
+	if err != nil {
+		if !leftDetached(err) {
+			return fmt.Errorf("Failed to switch session: %w", err)
+		}
+		ok = true
+	}
+
+	if ok {
+		logger.Printf("Switched to existing session: %s\n", item.Id)
+		recordHistory(history.ActionSwitch, item)
+		return nil
+	}
+
+	logger.Printf("Creating new session: %s\n", item.Id)
+	if err := multiService.CreateSession(item, projectConfig); err != nil && !leftDetached(err) {
+		return fmt.Errorf("Failed to create session: %w", err)
+	}
+	logger.Printf("Session created successfully: %s\n", item.Id)
+	recordHistory(history.ActionCreate, item)
+	return nil
+}
+
+// openSessions creates the sessions of all items but the last one in the
+// background and switches to the session of the last one. A session which
+// fails to open does not stop the others: the client switches to the last
+// session which did open, and the failures are printed to stderr and
+// returned.
+func openSessions(multiService *orchestrator.OrchestratorService, config *conf.Config, items []dataproviders.Item) error {
+	// Looked up once per item, as it may ask to trust a repo config
+	projectConfigs := make([]conf.ProjectConfig, len(items))
+	for i := range items {
+		projectConfigs[i] = getProjectConfig(config, &items[i])
+	}
+
+	var errs []error
+	opened := -1
+	last := len(items) - 1
+	for i := range items[:last] {
+		item := &items[i]
+		logger.Printf("Opening session in the background: %s\n", item.Id)
+		created, err := multiService.EnsureSession(item, projectConfigs[i])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to open session %s: %w", item.Id, err))
+			continue
+		}
+		if created {
+			recordHistory(history.ActionCreate, item)
+		}
+		opened = i
+	}
+
+	// Attaching outside of tmux blocks until the client detaches, so the
+	// failures so far are printed first
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	err := switchOrCreate(multiService, &items[last], projectConfigs[last])
+	if err == nil {
+		return errors.Join(errs...)
+	}
+
+	err = fmt.Errorf("failed to open session %s: %w", items[last].Id, err)
+	fmt.Fprintln(os.Stderr, err)
+	errs = append(errs, err)
+	if opened < 0 {
+		logger.Fatalf("Failed to open sessions: %v\n", errors.Join(errs...))
+	}
+
+	// The session was opened in the background, so this only switches to it
+	if err := switchOrCreate(multiService, &items[opened], projectConfigs[opened]); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// leftDetached reports whether err only means that there was no terminal to
+// attach to. The session is open then, so this is printed to stderr instead of
+// failing.
+func leftDetached(err error) bool {
+	if !errors.Is(err, tmux.ErrNoTerminal) {
+		return false
+	}
+	fmt.Fprintln(os.Stderr, err)
+	return true
+}
+
+// recordHistory adds the opened session to the history. The session is open
+// either way, so a failure is only logged.
+func recordHistory(action string, item *dataproviders.Item) {
+	store, err := history.DefaultStore()
+	if err == nil {
+		err = store.Record(history.Entry{Time: time.Now(), Action: action, Id: item.Id, Path: item.Path, ParentId: item.ParentId, ParentPath: item.ParentPath})
+	}
+	if err != nil {
+		logger.Printf("Failed to record history: %v\n", err)
+	}
+}
+
+// getProjectConfig returns the project config of the item, with the repo
+// config of the project merged over it if the user trusts it
+func getProjectConfig(config *conf.Config, item *dataproviders.Item) conf.ProjectConfig {
+	projectConfig, err := config.GetProjectConfig(item)
+	if err != nil {
+		logger.Fatalf("Failed to get project config: %v\n", err)
+	}
+
+	repo, err := findRepoConfig(item)
+	if err != nil {
+		logger.Fatalf("Failed to load repo config: %v\n", err)
+	}
+	if repo == nil {
+		return projectConfig
+	}
+
+	store, err := conf.DefaultTrustStore()
+	if err != nil {
+		logger.Fatalf("Failed to open trust store: %v\n", err)
+	}
+
+	trusted, err := store.IsTrusted(repo)
+	if err != nil {
+		logger.Fatalf("Failed to read trust store: %v\n", err)
+	}
+
+	if !trusted && promptTrust(repo) {
+		if err := store.Trust(repo); err != nil {
+			logger.Fatalf("Failed to trust repo config: %v\n", err)
+		}
+		trusted = true
+	}
+
+	if !trusted {
+		fmt.Fprintf(os.Stderr, "Ignoring untrusted %s, run 'mux-session trust %s' to apply it\n", repo.File, item.Path)
+		return projectConfig
+	}
+
+	logger.Printf("Applying repo config %s\n", repo.File)
+	return conf.MergeRepoConfig(projectConfig, repo)
+}
+
+// findRepoConfig loads the repo config of the project of the item. Items of
+// running sessions carry the session name instead of a directory as their path,
+// which must not be looked up relative to the working directory.
+func findRepoConfig(item *dataproviders.Item) (*conf.RepoConfig, error) {
+	if !filepath.IsAbs(item.Path) {
+		return nil, nil
+	}
+	return conf.FindRepoConfig(item.Path)
+}
+
+// promptTrust asks the user whether to trust a repo config which is new or
+// changed since it was trusted
+func promptTrust(repo *conf.RepoConfig) bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "%s is new or has changed. It can run commands in your shell.\n", repo.File)
+	fmt.Fprint(os.Stderr, "Trust it? [y/N] ")
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
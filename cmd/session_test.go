@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adrg/xdg"
+	"github.com/niedch/mux-session/internal/conf"
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/niedch/mux-session/internal/history"
+	"github.com/niedch/mux-session/internal/hooks"
+	"github.com/niedch/mux-session/internal/orchestrator"
+	"github.com/niedch/mux-session/internal/tmux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSessionTest loads a config with on_create and on_switch hooks and keeps
+// the history in a temporary directory
+func newSessionTest(t *testing.T) *conf.Config {
+	t.Helper()
+
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+search_paths = []
+
+[default]
+on_create = "echo created"
+on_switch = "echo switched"
+
+[[default.window]]
+window_name = "shell"
+`), 0o644))
+
+	config, err := conf.Load(configFile)
+	require.NoError(t, err)
+	return config
+}
+
+func historyEntries(t *testing.T) []string {
+	t.Helper()
+
+	store, err := history.DefaultStore()
+	require.NoError(t, err)
+	entries, err := store.Entries()
+	require.NoError(t, err)
+
+	var recorded []string
+	for _, entry := range entries {
+		recorded = append(recorded, entry.Action+" "+entry.Id)
+	}
+	return recorded
+}
+
+func TestOpenSessions(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []string
+		failOn  string
+		hooks   []string
+		history []string
+		current string
+	}{
+		{
+			name:    "single new session",
+			ids:     []string{"api"},
+			hooks:   []string{"on_create api"},
+			history: []string{"create api"},
+			current: "api",
+		},
+		{
+			name:    "marked new sessions",
+			ids:     []string{"api", "web"},
+			hooks:   []string{"on_create api", "on_create web"},
+			history: []string{"create api", "create web"},
+			current: "web",
+		},
+		{
+			name:    "last session fails",
+			ids:     []string{"api", "web"},
+			failOn:  "web",
+			hooks:   []string{"on_create api", "on_switch api"},
+			history: []string{"create api", "switch api"},
+			current: "api",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newSessionTest(t)
+
+			recorder := tmux.NewRecorder()
+			if tt.failOn != "" {
+				recorder.FailOn("NewSession", tt.failOn, errors.New("new-session failed"))
+			}
+			var ran []string
+			service := orchestrator.New(recorder).WithHookRunner(func(h hooks.Hook) error {
+				ran = append(ran, h.Event+" "+h.Session)
+				return nil
+			})
+
+			var items []dataproviders.Item
+			for _, id := range tt.ids {
+				items = append(items, dataproviders.Item{Id: id, Display: id, Path: filepath.Join(t.TempDir(), id)})
+			}
+
+			err := openSessions(service, config, items)
+			if tt.failOn != "" {
+				assert.ErrorContains(t, err, "failed to open session "+tt.failOn)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.hooks, ran, "one hook per new session")
+			assert.Equal(t, tt.history, historyEntries(t), "one history entry per new session")
+			assert.Equal(t, tt.current, recorder.CurrentSession)
+		})
+	}
+}
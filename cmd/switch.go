@@ -6,7 +6,6 @@ import (
 	"github.com/niedch/mux-session/internal/conf"
 	"github.com/niedch/mux-session/internal/dataproviders"
 	"github.com/niedch/mux-session/internal/logger"
-	"github.com/niedch/mux-session/internal/orchestrator"
 	"github.com/niedch/mux-session/internal/tmux"
 	"github.com/spf13/cobra"
 )
@@ -29,10 +28,11 @@ The ID can be a session name or directory path.`,
 		logger.Printf("Configuration loaded successfully\n")
 
 		logger.Printf("Initializing tmux wrapper (socket: %s)\n", socket)
-		tmux, err := tmux.NewTmux(socket)
+		tmux, err := tmux.NewControlTmux(socket)
 		if err != nil {
 			logger.Fatalf("Failed to initialize tmux: %v\n", err)
 		}
+		defer tmux.Close()
 
 		directoryProvider := dataproviders.NewDirectoryProvider(config.SearchPaths)
 		tmuxProvider := dataproviders.NewTmuxProvider(tmux)
@@ -53,25 +53,7 @@ The ID can be a session name or directory path.`,
 		}
 		logger.Printf("Found item: id=%s, display=%s\n", item.Id, item.Display)
 
-		multiService := orchestrator.New(tmux)
-		projectConfig := config.GetProjectConfig(item)
-
-		logger.Printf("Switching to session: %s\n", item.Id)
-		ok, err := multiService.SwitchSession(item)
-		if err != nil {
-			logger.Fatalf("Failed to switch session: %v\n", err)
-		}
-
-		if ok {
-			logger.Printf("Switched to existing session: %s\n", item.Id)
-			return
-		}
-
-		logger.Printf("Creating new session: %s\n", item.Id)
-		if err := multiService.CreateSession(item, projectConfig); err != nil {
-			logger.Fatalf("Failed to create session: %v\n", err)
-		}
-		logger.Printf("Session created successfully: %s\n", item.Id)
+		openSession(newOrchestrator(tmux, config), config, item)
 	},
 }
 
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/niedch/mux-session/internal/conf"
+	"github.com/niedch/mux-session/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var revokeTrust bool
+
+var trustCmd = &cobra.Command{
+	Use:   "trust [dir]",
+	Short: "Trust the repo config of a project",
+	Long: `Adds the .mux-session.toml or .mux-session/config.toml of the project in dir,
+or the current directory, to the allowlist of repo configs which are merged
+over the global config. The file has to be trusted again whenever it changes.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if verbose {
+			logger.SetEnabled(true)
+		}
+
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		}
+
+		repo, err := conf.FindRepoConfig(dir)
+		if err != nil {
+			logger.Fatalf("Failed to load repo config: %v\n", err)
+		}
+		if repo == nil {
+			logger.Fatalf("No repo config found in %s\n", dir)
+		}
+
+		store, err := conf.DefaultTrustStore()
+		if err != nil {
+			logger.Fatalf("Failed to open trust store: %v\n", err)
+		}
+
+		if revokeTrust {
+			if err := store.Revoke(repo); err != nil {
+				logger.Fatalf("Failed to revoke trust: %v\n", err)
+			}
+			fmt.Printf("Revoked trust in %s\n", repo.File)
+			return
+		}
+
+		if err := store.Trust(repo); err != nil {
+			logger.Fatalf("Failed to trust repo config: %v\n", err)
+		}
+		fmt.Printf("Trusted %s\n", repo.File)
+	},
+}
+
+func init() {
+	trustCmd.Flags().BoolVar(&revokeTrust, "revoke", false, "Remove the repo config from the allowlist")
+	rootCmd.AddCommand(trustCmd)
+}
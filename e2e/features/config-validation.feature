@@ -48,3 +48,77 @@ Feature: Config validation
     Then I should see the following items in output:
       | lines                              |
       | panel_direction must be 'v' or 'h' |
+
+  Scenario: Invalid command templates result in error
+    Given a new tmux server
+    And I have the following directories:
+      | name       |
+      | my-project |
+    When I run mux-session config-validate with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[project]]
+      name = "my-project"
+
+      [[project.window]]
+      window_name = "Main"
+      cmd = "cd {{.Path"
+      """
+    Then I should see the following items in output:
+      | lines                                  |
+      | invalid cmd template in window Main: .* |
+
+  Scenario: Key bindings accept a single key or a list of keys
+    Given a new tmux server
+    And I have the following directories:
+      | name       |
+      | my-project |
+    When I run mux-session config-validate with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [keys]
+      up = "ctrl+k"
+      kill = ["alt+x", "ctrl+x"]
+      """
+    Then I should see the following lines in output:
+      | lines     |
+      | "alt\+x",  |
+      | "ctrl\+k"  |
+
+  Scenario: Conflicting key bindings result in error
+    Given a new tmux server
+    And I have the following directories:
+      | name       |
+      | my-project |
+    When I run mux-session config-validate with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [keys]
+      kill = "ctrl+s"
+      """
+    Then I should see the following lines in output:
+      | lines                                        |
+      | key "ctrl\+s" is bound to both pin and kill |
+
+  Scenario: Explain which project config applies to an item
+    Given a new tmux server
+    And I have the following directories:
+      | name            |
+      | users-service   |
+    When I run mux-session config-validate --explain "users-service" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[project]]
+      match = "*-service"
+
+      [[project.window]]
+      window_name = "Service"
+      """
+    Then I should see the following lines in output:
+      | lines                                                                                                      |
+      | project with match "\*-service" matches directory name users-service \(most specific of 1 matching patterns\) |
+      | "WindowName": "Service"                                                                                    |
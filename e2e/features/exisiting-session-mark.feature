@@ -35,9 +35,9 @@ Feature: Mux Session mark existing Sessions
     Then I should see the following items in output:
       | item               |
       |  test-session     |
-      | [] .*/project-one |
       | 󰄱 .*/project-three |
       | 󰄱 .*/project-two   |
+      | [] .*/project-one |
 
   Scenario: Tmux internal Sessions should not be marked with "[ ]"
     Given a new tmux server
@@ -0,0 +1,60 @@
+Feature: Project config inheritance
+  As a user
+  I want projects to extend a shared template
+  So that I do not have to copy the same windows into every project
+
+  Scenario: Project extends a template and tweaks its windows
+    Given a new tmux server
+    And I have the following directories:
+      | name       |
+      | my-project |
+    When I run mux-session switch "my-project" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[template]]
+      name = "base"
+
+      [[template.window]]
+      window_name = "Main"
+
+      [[template.window]]
+      window_name = "Git"
+
+      [[template.window]]
+      window_name = "Scratch"
+
+      [[project]]
+      name = "my-project"
+      extends = "base"
+      remove_windows = ["Git"]
+
+      [[project.window]]
+      window_name = "Runner"
+      """
+    Then session "my-project" contains following windows:
+      | window_name |
+      | Main        |
+      | Scratch     |
+      | Runner      |
+
+  Scenario: Extends cycles result in error
+    Given a new tmux server
+    And I have the following directories:
+      | name       |
+      | my-project |
+    When I run mux-session config-validate with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[template]]
+      name = "a"
+      extends = "b"
+
+      [[template]]
+      name = "b"
+      extends = "a"
+      """
+    Then I should see the following items in output:
+      | lines                              |
+      | extends cycle detected: a -> b -> a |
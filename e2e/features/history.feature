@@ -0,0 +1,69 @@
+Feature: Session history
+  As a user of mux-session
+  I want to flip back to the project I used before
+  So that I do not have to search for it again
+
+  Scenario: Last switches to the previous project and recreates it if it was killed
+    Given a new tmux server
+    And I have the following directories:
+      | name        |
+      | project-one |
+      | project-two |
+    When I run mux-session switch "project-one" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [default]
+      [[default.window]]
+      window_name = "Shell"
+      """
+    And I run mux-session switch "project-two" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [default]
+      [[default.window]]
+      window_name = "Shell"
+      """
+    And I kill session "project-one"
+    And I run mux-session last with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [default]
+      [[default.window]]
+      window_name = "Editor"
+      """
+    Then session "project-one" contains following windows:
+      | window |
+      | Editor |
+    When I run mux-session history with config:
+      """
+      search_paths = ["<search_path>"]
+      """
+    Then I should see the following lines in output:
+      | line                                |
+      | create\s+project-one\s+.*/project-one |
+      | create\s+project-two\s+.*/project-two |
+      | create\s+project-one\s+.*/project-one |
+
+  Scenario: Last without a previous session fails
+    Given a new tmux server
+    And I have the following directories:
+      | name        |
+      | project-one |
+    When I run mux-session switch "project-one" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [default]
+      [[default.window]]
+      window_name = "Shell"
+      """
+    And I try to run mux-session last with config:
+      """
+      search_paths = ["<search_path>"]
+      """
+    Then I should see the following lines in output:
+      | line                               |
+      | No previous session in the history |
@@ -0,0 +1,54 @@
+Feature: Session lifecycle hooks
+  As a user
+  I want to run commands when sessions are created or switched to
+  So that I can prepare my project environment automatically
+
+  Scenario: on_create runs in the project directory with session env vars
+    Given a new tmux server
+    And I have the following directories:
+      | name       |
+      | my-project |
+    When I run mux-session switch "my-project" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[project]]
+      name = "my-project"
+      on_create = "echo created $MUX_SESSION_NAME > status"
+
+      [[project.window]]
+      window_name = "Main"
+      """
+    Then file "status" in directory "my-project" contains "created my-project"
+    And session "my-project" contains following windows:
+      | window_name |
+      | Main        |
+
+  Scenario: on_detach does not run when mux-session itself detaches
+    Given a new tmux server
+    And I have the following directories:
+      | name       |
+      | my-project |
+    When I run mux-session switch "my-project" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[project]]
+      name = "my-project"
+      on_detach = "touch detached"
+
+      [[project.window]]
+      window_name = "Main"
+      """
+    And I run mux-session switch "my-project" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[project]]
+      name = "my-project"
+      on_detach = "touch detached"
+
+      [[project.window]]
+      window_name = "Main"
+      """
+    Then file "detached" in directory "my-project" is not created
@@ -0,0 +1,29 @@
+Feature: Pinned projects
+  As a user of mux-session
+  I want to pin the projects I use all the time
+  So that they are always right next to the cursor
+
+  Scenario: Pinned projects are listed in their own group at the bottom
+    Given a new tmux server
+    And I have the following directories:
+      | name          |
+      | project-one   |
+      | project-two   |
+      | project-three |
+    When I run mux-session pin "project-one"
+    And I run mux-session list-sessions with config:
+      """
+      search_paths = ["<search_path>"]
+      """
+    Then I should see the following items in output:
+      | item                 |
+      | 󰄱 .*/project-three   |
+      | 󰄱 .*/project-two     |
+      | ^Pinned$             |
+      | 󰐃 󰄱 .*/project-one |
+    When I run mux-session unpin "project-one"
+    And I run mux-session list-sessions with config:
+      """
+      search_paths = ["<search_path>"]
+      """
+    Then I should not see "Pinned" in output
@@ -0,0 +1,45 @@
+Feature: Reconcile existing sessions
+  As a user
+  I want existing sessions to pick up windows added to my project config
+  So that I do not have to recreate my sessions
+
+  Scenario: Reconcile creates missing windows and keeps extra windows
+    Given a new tmux server
+    And I have the following directories:
+      | name       |
+      | my-project |
+    When I run mux-session switch "my-project" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[project]]
+      name = "my-project"
+
+      [[project.window]]
+      window_name = "Main"
+
+      [[project.window]]
+      window_name = "Scratch"
+      """
+    And I run mux-session reconcile "my-project" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[project]]
+      name = "my-project"
+
+      [[project.window]]
+      window_name = "Main"
+
+      [[project.window]]
+      window_name = "Runner"
+      """
+    Then I should see the following lines in output:
+      | lines                                         |
+      | Created window Runner                         |
+      | Window Scratch is not in the project config   |
+    And session "my-project" contains following windows:
+      | window_name |
+      | Main        |
+      | Scratch     |
+      | Runner      |
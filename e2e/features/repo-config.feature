@@ -0,0 +1,58 @@
+Feature: Per-repo project config
+  As a user
+  I want projects to carry their own session layout
+  So that the team can commit the standard layout of each project
+
+  Scenario: Trusted repo config is merged over the global config
+    Given a new tmux server
+    And I have the following directories:
+      | name       |
+      | my-project |
+    And directory "my-project" contains file ".mux-session.toml" with:
+      """
+      remove_windows = ["Scratch"]
+
+      [[window]]
+      window_name = "Server"
+      """
+    When I trust the repo config of directory "my-project"
+    And I run mux-session switch "my-project" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[project]]
+      name = "my-project"
+
+      [[project.window]]
+      window_name = "Main"
+
+      [[project.window]]
+      window_name = "Scratch"
+      """
+    Then session "my-project" contains following windows:
+      | window_name |
+      | Main        |
+      | Server      |
+    And session "my-project" does not contain window "Scratch"
+
+  Scenario: Untrusted repo config is ignored
+    Given a new tmux server
+    And I have the following directories:
+      | name       |
+      | my-project |
+    And directory "my-project" contains file ".mux-session/config.toml" with:
+      """
+      [[window]]
+      window_name = "Server"
+      """
+    When I run mux-session switch "my-project" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[default.window]]
+      window_name = "Main"
+      """
+    Then session "my-project" contains following windows:
+      | window_name |
+      | Main        |
+    And session "my-project" does not contain window "Server"
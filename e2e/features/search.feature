@@ -35,8 +35,8 @@ Feature: Search functionality
     And I search for "project"
     Then I should see the following items in output:
       | item          |
-      | project-beta  |
       | project-alpha |
+      | project-beta  |
     And I should not see "random-dir" in output
 
   Scenario: List sessions with multiple search results
@@ -55,6 +55,64 @@ Feature: Search functionality
     Then I should see the following items in output:
       | item          |
       | project       |
-      | project-beta  |
       | project-alpha |
+      | project-beta  |
     And I should not see "random-dir" in output
+
+  Scenario: Matches on word starts rank above scattered matches
+    Given a new tmux server
+    And I have the following directories:
+      | name        |
+      | mux-session |
+      | programs    |
+    When I run mux-session list-sessions with config:
+      """
+      search_paths = ["<search_path>"]
+      """
+    And I search for "ms"
+    Then I should see the following items in output:
+      | item        |
+      | programs    |
+      | mux-session |
+
+  Scenario: Filter on language and tags with key:value
+    Given a new tmux server
+    And I have the following directories:
+      | name    |
+      | api     |
+      | web     |
+      | scripts |
+    And directory "api" contains file "go.mod" with:
+      """
+      module api
+      """
+    And directory "scripts" contains file "go.mod" with:
+      """
+      module scripts
+      """
+    When I search for "lang:go" with config:
+      """
+      search_paths = ["<search_path>"]
+      """
+    Then I should see the following items in output:
+      | item    |
+      | api     |
+      | scripts |
+    And I should not see "web" in output
+    When I search for "tag:backend" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[project]]
+      name = "api"
+      tags = ["backend"]
+
+      [[project]]
+      name = "web"
+      tags = ["frontend", "backend"]
+      """
+    Then I should see the following items in output:
+      | item |
+      | api  |
+      | web  |
+    And I should not see "scripts" in output
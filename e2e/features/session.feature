@@ -97,3 +97,44 @@ Feature: Mux Session functionality
     And session "my-second-project" contains following windows:
       | window_name |
       | Editor      |
+
+  Scenario: Project config applies through a match pattern
+    Given a new tmux server
+    And I have the following directories:
+      | name          |
+      | users-service |
+    When I run mux-session switch "users-service" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [[project]]
+      match = "*-service"
+
+      [[project.window]]
+      window_name = "Service"
+      """
+    Then session "users-service" contains following windows:
+      | window_name |
+      | Service     |
+
+  Scenario: Search path table searches nested directories for project markers
+    Given a new tmux server
+    And I have the following directories:
+      | name                      |
+      | acme/api/.git             |
+      | acme/web/.git             |
+      | acme/docs                 |
+      | other/tool/.git           |
+      | other/node_modules/x/.git |
+    When I run mux-session list-sessions with config:
+      """
+      search_paths = [{ path = "<search_path>", max_depth = 3, markers = [".git"], exclude = ["node_modules"] }]
+      """
+    Then I should see the following items in output:
+      | item         |
+      | test-session |
+      | acme/api$    |
+      | acme/web$    |
+      | other/tool$  |
+    And I should not see "docs" in output
+    And I should not see "node_modules" in output
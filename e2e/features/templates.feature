@@ -0,0 +1,28 @@
+Feature: Command templates
+  As a user
+  I want to reference the selected project in window commands
+  So that a shared default config works for every project
+
+  Scenario: Window commands expand template variables
+    Given a new tmux server
+    And I have the following directories:
+      | name       |
+      | my-project |
+    When I run mux-session switch "my-project" with config:
+      """
+      search_paths = ["<search_path>"]
+
+      [default.env]
+      GREETING = "hello"
+
+      [[default.window]]
+      window_name = "Main"
+      cmd = "echo {{.Env.GREETING}}-{{.Name}}"
+      """
+    And I execute following Command in Session "my-project" on Window "Main":
+      """
+      true
+      """
+    Then I should see the following items in output:
+      | item             |
+      | hello-my-project |
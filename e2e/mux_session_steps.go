@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/cucumber/godog"
 	"github.com/stretchr/testify/assert"
@@ -46,6 +47,21 @@ func RegisterMuxSessionSteps(ctx *godog.ScenarioContext) {
 		return nil
 	})
 
+	ctx.Step(`^directory "([^"]*)" contains file "([^"]*)" with:$`, func(ctx context.Context, dirName, fileName string, content *godog.DocString) error {
+		testCtx := ctx.Value("testCtx").(*testContext)
+
+		filePath := filepath.Join(testCtx.tempDir, dirName, fileName)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %v", fileName, err)
+		}
+		return os.WriteFile(filePath, []byte(content.Content), 0644)
+	})
+
+	ctx.Step(`^I trust the repo config of directory "([^"]*)"$`, func(ctx context.Context, dirName string) error {
+		testCtx := ctx.Value("testCtx").(*testContext)
+		return executeCommandStep("./mux-session", "trust", filepath.Join(testCtx.tempDir, dirName))(ctx)
+	})
+
 	ctx.Step(`^I run mux-session list-sessions with config:$`, func(ctx context.Context, docString *godog.DocString) error {
 		err := executeMuxSessionWithConfig("list-sessions")(ctx, docString)
 		if err != nil {
@@ -64,6 +80,55 @@ func RegisterMuxSessionSteps(ctx *godog.ScenarioContext) {
 		return nil
 	})
 
+	ctx.Step(`^I run mux-session (last|history) with config:$`, func(ctx context.Context, cmd string, docString *godog.DocString) error {
+		return executeMuxSessionWithConfig(cmd)(ctx, docString)
+	})
+
+	ctx.Step(`^I run mux-session (pin|unpin) "([^"]*)"$`, func(ctx context.Context, cmd, id string) error {
+		return executeCommandStep("./mux-session", cmd, id)(ctx)
+	})
+
+	ctx.Step(`^I try to run mux-session last with config:$`, func(ctx context.Context, docString *godog.DocString) error {
+		if err := executeMuxSessionWithConfig("last")(ctx, docString); err == nil {
+			return fmt.Errorf("expected mux-session last to fail")
+		}
+		return nil
+	})
+
+	ctx.Step(`^I run mux-session reconcile "([^"]*)" with config:$`, func(ctx context.Context, dirName string, docString *godog.DocString) error {
+		return executeMuxSessionWithConfig("reconcile", dirName)(ctx, docString)
+	})
+
+	ctx.Step(`^file "([^"]*)" in directory "([^"]*)" contains "([^"]*)"$`, func(ctx context.Context, fileName, dirName, expected string) error {
+		testCtx := ctx.Value("testCtx").(*testContext)
+
+		content, err := os.ReadFile(filepath.Join(testCtx.tempDir, dirName, fileName))
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %v", fileName, err)
+		}
+
+		if strings.TrimSpace(string(content)) != expected {
+			return fmt.Errorf("expected file %s to contain '%s', got '%s'", fileName, expected, strings.TrimSpace(string(content)))
+		}
+		return nil
+	})
+
+	ctx.Step(`^file "([^"]*)" in directory "([^"]*)" is not created$`, func(ctx context.Context, fileName, dirName string) error {
+		testCtx := ctx.Value("testCtx").(*testContext)
+
+		// Hooks run in the background, so give them time to create the file
+		time.Sleep(time.Second)
+		if _, err := os.Stat(filepath.Join(testCtx.tempDir, dirName, fileName)); err == nil {
+			return fmt.Errorf("expected file %s not to be created", fileName)
+		}
+		return nil
+	})
+
+	ctx.Step(`^I run mux-session config-validate --explain "([^"]*)" with config:$`, func(ctx context.Context, dirName string, docString *godog.DocString) error {
+		testCtx := ctx.Value("testCtx").(*testContext)
+		return executeMuxSessionWithConfig("config-validate", "--explain", dirName, "-L", testCtx.tmuxSessionName)(ctx, docString)
+	})
+
 	ctx.Step(`^I run mux-session config-validate with config:$`, func(ctx context.Context, docString *godog.DocString) error {
 		testCtx := ctx.Value("testCtx").(*testContext)
 		err := executeMuxSessionWithConfig("config-validate")(ctx, docString)
@@ -110,6 +175,10 @@ func RegisterMuxSessionSteps(ctx *godog.ScenarioContext) {
 		return executeMuxSessionWithConfig("list-sessions", "-s", searchQuery)(ctx, &godog.DocString{Content: "search_paths = [\"<search_path>\"]"})
 	})
 
+	ctx.Step(`^I search for "([^"]*)" with config:$`, func(ctx context.Context, searchQuery string, docString *godog.DocString) error {
+		return executeMuxSessionWithConfig("list-sessions", "-s", searchQuery)(ctx, docString)
+	})
+
 	ctx.Step(`^I should not see "([^"]*)" in output$`, func(ctx context.Context, text string) error {
 		testCtx := ctx.Value("testCtx").(*testContext)
 		assert.False(godog.T(ctx), strings.Contains(testCtx.lastOutput, text), "Expected output '%s' to NOT contain: '%s'", testCtx.lastOutput, text)
@@ -22,6 +22,13 @@ func InitializeScenario(ctx *godog.ScenarioContext) {
 	ctx.Before(func(ctx context.Context, sc *godog.Scenario) (context.Context, error) {
 		testCtx := &testContext{lastOutput: "", tmuxSessionName: fmt.Sprintf("test-%s", sc.Id)}
 
+		// State like the session history must not leak into other scenarios
+		if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+			if err := os.RemoveAll(filepath.Join(dataHome, "mux-session")); err != nil {
+				return ctx, err
+			}
+		}
+
 		return context.WithValue(ctx, "testCtx", testCtx), nil
 	})
 
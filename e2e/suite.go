@@ -9,7 +9,17 @@ import (
 )
 
 func InitializeSuite(ctx *godog.TestSuiteContext) {
+	var dataHome string
+
 	ctx.BeforeSuite(func() {
+		// Keep state like trusted repo configs out of the user's data directory
+		var err error
+		dataHome, err = os.MkdirTemp("", "mux-session-data-*")
+		if err != nil {
+			panic(fmt.Sprintf("Failed to create data directory: %v", err))
+		}
+		os.Setenv("XDG_DATA_HOME", dataHome)
+
 		// Build the binary before running tests
 		cmd := exec.Command("go", "build", "-o", "mux-session", "..")
 		if output, err := cmd.CombinedOutput(); err != nil {
@@ -22,5 +32,7 @@ func InitializeSuite(ctx *godog.TestSuiteContext) {
 		if _, err := os.Stat("mux-session"); err == nil {
 			exec.Command("rm", "mux-session").Run()
 		}
+
+		os.RemoveAll(dataHome)
 	})
 }
@@ -13,10 +13,8 @@ import (
 
 func RegisterTmuxSteps(ctx *godog.ScenarioContext) {
 	ctx.Step(`^a new tmux server$`, func(ctx context.Context) error {
-		if err := executeTmuxCommand("tmux", "start-server")(ctx); err != nil {
-			return err
-		}
-
+		// new-session starts the server. A server started on its own exits
+		// again right away without sessions, racing with the new session.
 		if err := executeTmuxCommand("tmux", "new-session", "-d", "-s", "test-session")(ctx); err != nil {
 			return err
 		}
@@ -104,6 +102,10 @@ func RegisterTmuxSteps(ctx *godog.ScenarioContext) {
 
 	ctx.Step(`^I run list-sessions$`, executeTmuxCommand("tmux", "list-sessions"))
 
+	ctx.Step(`^I kill session "([^"]*)"$`, func(ctx context.Context, sessionName string) error {
+		return executeTmuxCommand("tmux", "kill-session", "-t", "="+sessionName)(ctx)
+	})
+
 	ctx.Step(`^I expect following sessions:$`, func(ctx context.Context, docString *godog.DocString) error {
 		testCtx := ctx.Value("testCtx").(*testContext)
 		if err := executeTmuxCommand("tmux", "list-sessions")(ctx); err != nil {
@@ -134,6 +136,18 @@ func RegisterTmuxSteps(ctx *godog.ScenarioContext) {
 		return nil
 	})
 
+	ctx.Step(`^session "([^"]*)" does not contain window "([^"]*)"$`, func(ctx context.Context, sessionName, windowName string) error {
+		testCtx := ctx.Value("testCtx").(*testContext)
+
+		output, err := executeCommand("tmux", "-L", testCtx.tmuxSessionName, "list-windows", "-t", sessionName, "-F", "#W")
+		if err != nil {
+			return err
+		}
+
+		assert.NotContains(godog.T(ctx), strings.Split(strings.TrimSpace(output), "\n"), windowName, "Expected session %s not to contain window: %s", sessionName, windowName)
+		return nil
+	})
+
 	ctx.Step(`^window "([^"]*)" in session "([^"]*)" has environment variable "([^"]*)" set to "([^"]*)"$`, func(ctx context.Context, windowName, sessionName, envVar, expectedValue string) error {
 		testCtx := ctx.Value("testCtx").(*testContext)
 
@@ -25,16 +25,45 @@ type WindowConfig struct {
 }
 
 type ProjectConfig struct {
-	Name         *string           `koanf:"name"`
-	WindowConfig []WindowConfig    `koanf:"window"`
-	Env          map[string]string `koanf:"env"`
+	Name          *string           `koanf:"name"`
+	Match         string            `koanf:"match"`
+	Extends       *string           `koanf:"extends"`
+	WindowConfig  []WindowConfig    `koanf:"window"`
+	RemoveWindows []string          `koanf:"remove_windows"`
+	Env           map[string]string `koanf:"env"`
+	OnCreate      string            `koanf:"on_create"`
+	OnSwitch      string            `koanf:"on_switch"`
+	OnDetach      string            `koanf:"on_detach"`
+	Tags          []string          `koanf:"tags"`
 }
 
+// Orders of the unfiltered picker list, set by sort. SortFrecency applies if
+// sort is unset.
+const (
+	SortFrecency = "frecency"
+	SortAlpha    = "alpha"
+	SortMtime    = "mtime"
+)
+
+// Texts of an item which picker queries are matched against, set by
+// search_field. SearchFieldDisplay applies if search_field is unset.
+const (
+	SearchFieldDisplay = "display"
+	SearchFieldPath    = "path"
+	SearchFieldId      = "id"
+)
+
 type Config struct {
-	SearchPaths     []string        `koanf:"search_paths"`
-	PreviewProvider *string         `koanf:"preview_provider"`
-	Default         ProjectConfig   `koanf:"default"`
-	Project         []ProjectConfig `koanf:"project"`
+	SearchPaths       []dataproviders.SearchPath `koanf:"search_paths"`
+	PreviewProvider   *string                    `koanf:"preview_provider"`
+	ReconcileOnSwitch bool                       `koanf:"reconcile_on_switch"`
+	Sort              string                     `koanf:"sort"`
+	SearchField       string                     `koanf:"search_field"`
+	CollapseWorktrees bool                       `koanf:"collapse_worktrees"`
+	Keys              map[string][]string        `koanf:"keys"`
+	Default           ProjectConfig              `koanf:"default"`
+	Project           []ProjectConfig            `koanf:"project"`
+	Template          []ProjectConfig            `koanf:"template"`
 }
 
 func Load(configFile string) (*Config, error) {
@@ -76,33 +105,48 @@ func loadProjectConfig(k *koanf.Koanf, configFile string) error {
 	return nil
 }
 
-// Finds Project Config otherwise returns Default
-func (c *Config) GetProjectConfig(item *dataproviders.Item) ProjectConfig {
-	configId := item.Id
-	if item.ParentId != "" {
-		configId = item.ParentId
+// Finds Project Config otherwise returns Default, with its extends chain
+// resolved
+func (c *Config) GetProjectConfig(item *dataproviders.Item) (ProjectConfig, error) {
+	match := c.MatchProject(item)
+
+	projectConfig := match.Project
+	if projectConfig == nil {
+		projectConfig = &c.Default
 	}
 
-	projectConfig := c.findProject(configId)
+	result, err := c.resolve(projectConfig)
+	if err != nil {
+		return ProjectConfig{}, err
+	}
 
-	var result ProjectConfig
-	if projectConfig == nil {
-		result = c.Default
-	} else {
-		result = *projectConfig
+	// A block matched by pattern covers many directories, so its name must
+	// not become the session name
+	if match.Pattern != "" {
+		result.Name = nil
 	}
 
 	if item.ParentId != "" && result.Name != nil {
 		result.Name = &item.Id
 	}
 
-	return result
+	return result, nil
+}
+
+// Tags returns the tags of the project config of item, which the picker can
+// filter on with tag:
+func (c *Config) Tags(item dataproviders.Item) []string {
+	projectConfig, err := c.GetProjectConfig(&item)
+	if err != nil {
+		return nil
+	}
+	return projectConfig.Tags
 }
 
 func (c *Config) findProject(dir string) *ProjectConfig {
-	for _, projectConfig := range c.Project {
-		if *projectConfig.Name == dir {
-			return &projectConfig
+	for i := range c.Project {
+		if c.Project[i].Name != nil && *c.Project[i].Name == dir {
+			return &c.Project[i]
 		}
 	}
 
@@ -110,7 +110,10 @@ func TestGetProjectConfig(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := tt.config.GetProjectConfig(tt.item)
+			result, err := tt.config.GetProjectConfig(tt.item)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
 
 			if result.Name == nil && tt.expected.Name != nil {
 				t.Errorf("Expected Name to be %s, but got nil", *tt.expected.Name)
@@ -1,15 +1,92 @@
 package conf
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/niedch/mux-session/internal/dataproviders"
+)
 
 func validateConfig(conf *Config) error {
+	if err := validateSearchPaths(conf.SearchPaths); err != nil {
+		return err
+	}
+
+	switch conf.Sort {
+	case "", SortFrecency, SortAlpha, SortMtime:
+	default:
+		return fmt.Errorf("invalid sort %q, expected %q, %q or %q", conf.Sort, SortFrecency, SortAlpha, SortMtime)
+	}
+
+	switch conf.SearchField {
+	case "", SearchFieldDisplay, SearchFieldPath, SearchFieldId:
+	default:
+		return fmt.Errorf("invalid search_field %q, expected %q, %q or %q", conf.SearchField, SearchFieldDisplay, SearchFieldPath, SearchFieldId)
+	}
+
+	if err := validateKeys(conf); err != nil {
+		return err
+	}
+
+	for _, template := range conf.Template {
+		if template.Name == nil || *template.Name == "" {
+			return errors.New("every template needs a name")
+		}
+	}
+
 	for _, project := range conf.Project {
-		if err := validateProjectConfig(project); err != nil {
-			return err
+		if project.Name == nil && project.Match == "" {
+			return errors.New("every project needs a name or a match pattern")
+		}
+
+		if project.Match != "" {
+			if err := validatePattern(project.Match); err != nil {
+				return fmt.Errorf("invalid match pattern %q: %w", project.Match, err)
+			}
 		}
 	}
 
-	return validateProjectConfig(conf.Default)
+	// Validate the configs as they are used, with their extends chain applied
+	for _, configs := range [][]ProjectConfig{conf.Template, conf.Project} {
+		for i := range configs {
+			project, err := conf.resolve(&configs[i])
+			if err != nil {
+				return err
+			}
+
+			if err := validateProjectConfig(project); err != nil {
+				return err
+			}
+		}
+	}
+
+	project, err := conf.resolve(&conf.Default)
+	if err != nil {
+		return err
+	}
+
+	return validateProjectConfig(project)
+}
+
+func validateSearchPaths(searchPaths []dataproviders.SearchPath) error {
+	for _, searchPath := range searchPaths {
+		if searchPath.Path == "" {
+			return errors.New("every search path needs a path")
+		}
+
+		if searchPath.MaxDepth < 0 {
+			return fmt.Errorf("max_depth of search path %s must not be negative", searchPath.Path)
+		}
+
+		for _, pattern := range searchPath.Exclude {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid exclude pattern %q of search path %s: %w", pattern, searchPath.Path, err)
+			}
+		}
+	}
+
+	return nil
 }
 
 func validateProjectConfig(project ProjectConfig) error {
@@ -21,6 +98,10 @@ func validateProjectConfig(project ProjectConfig) error {
 		return err
 	}
 
+	if err := validateCommandTemplates(project); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -52,3 +133,21 @@ func validatePanelConfig(project ProjectConfig) error {
 
 	return nil
 }
+
+func validateCommandTemplates(project ProjectConfig) error {
+	for _, window := range project.WindowConfig {
+		if window.Cmd != nil {
+			if _, err := parseCommand(*window.Cmd); err != nil {
+				return fmt.Errorf("invalid cmd template in window %s: %w", window.WindowName, err)
+			}
+		}
+
+		for i, panel := range window.PanelConfig {
+			if _, err := parseCommand(panel.Cmd); err != nil {
+				return fmt.Errorf("invalid cmd template in panel %d of window %s: %w", i, window.WindowName, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSearchPaths(t *testing.T) {
+	tests := []struct {
+		name       string
+		searchPath dataproviders.SearchPath
+		expected   string
+	}{
+		{name: "plain path", searchPath: dataproviders.SearchPath{Path: "/src"}},
+		{name: "table form", searchPath: dataproviders.SearchPath{Path: "/src", MaxDepth: 3, Markers: []string{".git"}, Exclude: []string{"node_modules"}}},
+		{name: "missing path", searchPath: dataproviders.SearchPath{MaxDepth: 2}, expected: "every search path needs a path"},
+		{name: "negative depth", searchPath: dataproviders.SearchPath{Path: "/src", MaxDepth: -1}, expected: "max_depth of search path /src must not be negative"},
+		{name: "invalid exclude", searchPath: dataproviders.SearchPath{Path: "/src", Exclude: []string{"[vendor"}}, expected: `invalid exclude pattern "[vendor" of search path /src`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfig(&Config{SearchPaths: []dataproviders.SearchPath{tt.searchPath}})
+			if tt.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestValidateSort(t *testing.T) {
+	for _, sort := range []string{"", SortFrecency, SortAlpha, SortMtime} {
+		assert.NoError(t, validateConfig(&Config{Sort: sort}), "sort %q", sort)
+	}
+
+	assert.ErrorContains(t, validateConfig(&Config{Sort: "random"}), `invalid sort "random"`)
+}
+
+func TestValidateSearchField(t *testing.T) {
+	for _, field := range []string{"", SearchFieldDisplay, SearchFieldPath, SearchFieldId} {
+		assert.NoError(t, validateConfig(&Config{SearchField: field}), "search_field %q", field)
+	}
+
+	assert.ErrorContains(t, validateConfig(&Config{SearchField: "name"}), `invalid search_field "name"`)
+}
+
+func TestValidateKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     map[string][]string
+		expected string
+	}{
+		{name: "defaults", keys: nil},
+		{name: "rebound action", keys: map[string][]string{KeyUp: {"ctrl+k", "up"}, KeyKill: {"alt+x"}}},
+		{name: "disabled action frees its key", keys: map[string][]string{KeyPin: {}, KeyKill: {"ctrl+s"}}},
+		{name: "named keys", keys: map[string][]string{KeyPageUp: {"ctrl+u", "shift+up", "f5", "alt+pgup"}}},
+		{name: "unknown action", keys: map[string][]string{"jump": {"ctrl+j"}}, expected: `invalid action "jump" in keys`},
+		{name: "unknown key", keys: map[string][]string{KeyUp: {"ctrl+up+down"}}, expected: `invalid key "ctrl+up+down" for up: unknown key name`},
+		{name: "never reported key", keys: map[string][]string{KeyUp: {"ctrl+i"}}, expected: `invalid key "ctrl+i"`},
+		{name: "plain character", keys: map[string][]string{KeyDown: {"j"}}, expected: `invalid key "j" for down: it would be typed into the search`},
+		{name: "conflict with default", keys: map[string][]string{KeyKill: {"ctrl+s"}}, expected: `key "ctrl+s" is bound to both pin and kill`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfig(&Config{Keys: tt.keys})
+			if tt.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestKeyBindings(t *testing.T) {
+	config := &Config{Keys: map[string][]string{KeyUp: {"ctrl+k"}, KeyPin: {}}}
+
+	bindings := config.KeyBindings()
+
+	assert.Equal(t, []string{"ctrl+k"}, bindings[KeyUp])
+	assert.Empty(t, bindings[KeyPin])
+	assert.Equal(t, DefaultKeys[KeyDown], bindings[KeyDown])
+	assert.Len(t, bindings, len(KeyActions))
+}
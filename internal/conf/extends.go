@@ -0,0 +1,146 @@
+package conf
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// resolve applies the extends chain of project. Settings closer to project
+// take precedence over the ones they extend.
+func (c *Config) resolve(project *ProjectConfig) (ProjectConfig, error) {
+	return c.resolveChain(project, []*ProjectConfig{project})
+}
+
+func (c *Config) resolveChain(project *ProjectConfig, chain []*ProjectConfig) (ProjectConfig, error) {
+	if project.Extends == nil || *project.Extends == "" {
+		return *project, nil
+	}
+
+	parent := c.findExtendable(*project.Extends)
+	if parent == nil {
+		return ProjectConfig{}, fmt.Errorf("cannot extend %s: no template or project with that name", *project.Extends)
+	}
+
+	if slices.Contains(chain, parent) {
+		return ProjectConfig{}, fmt.Errorf("extends cycle detected: %s", chainNames(append(chain, parent)))
+	}
+
+	resolvedParent, err := c.resolveChain(parent, append(chain, parent))
+	if err != nil {
+		return ProjectConfig{}, err
+	}
+
+	return mergeProjectConfig(resolvedParent, *project), nil
+}
+
+// findExtendable looks up a template by name, falling back to a project
+func (c *Config) findExtendable(name string) *ProjectConfig {
+	for i := range c.Template {
+		if c.Template[i].Name != nil && *c.Template[i].Name == name {
+			return &c.Template[i]
+		}
+	}
+
+	return c.findProject(name)
+}
+
+func chainNames(chain []*ProjectConfig) string {
+	names := make([]string, 0, len(chain))
+	for _, project := range chain {
+		switch {
+		case project.Name != nil:
+			names = append(names, *project.Name)
+		case project.Match != "":
+			names = append(names, fmt.Sprintf("match %q", project.Match))
+		default:
+			names = append(names, "default")
+		}
+	}
+
+	return strings.Join(names, " -> ")
+}
+
+// mergeProjectConfig lays child over parent. Windows are merged by
+// window_name, env by key, tags are combined, and windows in
+// child.RemoveWindows are dropped from the inherited ones. The name is never
+// inherited.
+func mergeProjectConfig(parent ProjectConfig, child ProjectConfig) ProjectConfig {
+	result := child
+	result.Extends = nil
+	result.RemoveWindows = nil
+
+	childHasPrimary := slices.ContainsFunc(child.WindowConfig, func(window WindowConfig) bool {
+		return window.Primary != nil && *window.Primary
+	})
+
+	windows := make([]WindowConfig, 0, len(parent.WindowConfig)+len(child.WindowConfig))
+	for _, window := range parent.WindowConfig {
+		if slices.Contains(child.RemoveWindows, window.WindowName) {
+			continue
+		}
+		// Only one window can be primary, and the child's choice wins
+		if childHasPrimary {
+			window.Primary = nil
+		}
+		windows = append(windows, window)
+	}
+
+	for _, window := range child.WindowConfig {
+		i := slices.IndexFunc(windows, func(inherited WindowConfig) bool {
+			return inherited.WindowName == window.WindowName
+		})
+		if i < 0 {
+			windows = append(windows, window)
+			continue
+		}
+		windows[i] = mergeWindowConfig(windows[i], window)
+	}
+	result.WindowConfig = windows
+
+	if len(parent.Env) > 0 || len(child.Env) > 0 {
+		result.Env = make(map[string]string, len(parent.Env)+len(child.Env))
+		for key, value := range parent.Env {
+			result.Env[key] = value
+		}
+		for key, value := range child.Env {
+			result.Env[key] = value
+		}
+	}
+
+	if len(parent.Tags) > 0 {
+		result.Tags = slices.Clone(parent.Tags)
+		for _, tag := range child.Tags {
+			if !slices.Contains(result.Tags, tag) {
+				result.Tags = append(result.Tags, tag)
+			}
+		}
+	}
+
+	if result.OnCreate == "" {
+		result.OnCreate = parent.OnCreate
+	}
+	if result.OnSwitch == "" {
+		result.OnSwitch = parent.OnSwitch
+	}
+	if result.OnDetach == "" {
+		result.OnDetach = parent.OnDetach
+	}
+
+	return result
+}
+
+func mergeWindowConfig(parent WindowConfig, child WindowConfig) WindowConfig {
+	result := parent
+	if child.Cmd != nil {
+		result.Cmd = child.Cmd
+	}
+	if child.Primary != nil {
+		result.Primary = child.Primary
+	}
+	if len(child.PanelConfig) > 0 {
+		result.PanelConfig = child.PanelConfig
+	}
+
+	return result
+}
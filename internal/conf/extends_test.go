@@ -0,0 +1,232 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProjectConfig_Extends(t *testing.T) {
+	base := ProjectConfig{
+		Name: stringPtr("base"),
+		WindowConfig: []WindowConfig{
+			{WindowName: "nvim", Cmd: stringPtr("nvim ."), Primary: boolPtr(true)},
+			{WindowName: "shell"},
+			{WindowName: "git", Cmd: stringPtr("lazygit")},
+			{WindowName: "perf", PanelConfig: []PanelConfig{{PanelDirection: "h", Cmd: "top"}}},
+		},
+		Env:      map[string]string{"EDITOR": "nvim", "STAGE": "dev"},
+		OnCreate: "direnv allow",
+	}
+
+	tests := []struct {
+		name     string
+		config   *Config
+		item     *dataproviders.Item
+		expected ProjectConfig
+	}{
+		{
+			name: "project without extends is unchanged",
+			config: &Config{
+				Template: []ProjectConfig{base},
+				Project:  []ProjectConfig{{Name: stringPtr("api"), WindowConfig: []WindowConfig{{WindowName: "nvim"}}}},
+			},
+			item:     &dataproviders.Item{Id: "api"},
+			expected: ProjectConfig{Name: stringPtr("api"), WindowConfig: []WindowConfig{{WindowName: "nvim"}}},
+		},
+		{
+			name: "windows merge by name and env merges by key",
+			config: &Config{
+				Template: []ProjectConfig{base},
+				Project: []ProjectConfig{{
+					Name:    stringPtr("api"),
+					Extends: stringPtr("base"),
+					WindowConfig: []WindowConfig{
+						{WindowName: "shell", Cmd: stringPtr("make watch")},
+						{WindowName: "db", Cmd: stringPtr("psql")},
+					},
+					Env: map[string]string{"STAGE": "test"},
+				}},
+			},
+			item: &dataproviders.Item{Id: "api"},
+			expected: ProjectConfig{
+				Name: stringPtr("api"),
+				WindowConfig: []WindowConfig{
+					{WindowName: "nvim", Cmd: stringPtr("nvim ."), Primary: boolPtr(true)},
+					{WindowName: "shell", Cmd: stringPtr("make watch")},
+					{WindowName: "git", Cmd: stringPtr("lazygit")},
+					{WindowName: "perf", PanelConfig: []PanelConfig{{PanelDirection: "h", Cmd: "top"}}},
+					{WindowName: "db", Cmd: stringPtr("psql")},
+				},
+				Env:      map[string]string{"EDITOR": "nvim", "STAGE": "test"},
+				OnCreate: "direnv allow",
+			},
+		},
+		{
+			name: "tags are combined",
+			config: &Config{
+				Template: []ProjectConfig{{Name: stringPtr("service"), Tags: []string{"backend", "api"}}},
+				Project:  []ProjectConfig{{Name: stringPtr("api"), Extends: stringPtr("service"), Tags: []string{"api", "billing"}}},
+			},
+			item:     &dataproviders.Item{Id: "api"},
+			expected: ProjectConfig{Name: stringPtr("api"), WindowConfig: []WindowConfig{}, Tags: []string{"backend", "api", "billing"}},
+		},
+		{
+			name: "remove_windows drops inherited windows and primary moves to the child",
+			config: &Config{
+				Template: []ProjectConfig{base},
+				Project: []ProjectConfig{{
+					Name:          stringPtr("api"),
+					Extends:       stringPtr("base"),
+					RemoveWindows: []string{"git", "perf"},
+					WindowConfig:  []WindowConfig{{WindowName: "shell", Primary: boolPtr(true)}},
+				}},
+			},
+			item: &dataproviders.Item{Id: "api"},
+			expected: ProjectConfig{
+				Name: stringPtr("api"),
+				WindowConfig: []WindowConfig{
+					{WindowName: "nvim", Cmd: stringPtr("nvim .")},
+					{WindowName: "shell", Primary: boolPtr(true)},
+				},
+				Env:      map[string]string{"EDITOR": "nvim", "STAGE": "dev"},
+				OnCreate: "direnv allow",
+			},
+		},
+		{
+			name: "chains through projects and applies to default",
+			config: &Config{
+				Template: []ProjectConfig{base},
+				Project: []ProjectConfig{{
+					Name:          stringPtr("api"),
+					Extends:       stringPtr("base"),
+					RemoveWindows: []string{"perf", "git"},
+					OnCreate:      "make deps",
+				}},
+				Default: ProjectConfig{
+					Extends:      stringPtr("api"),
+					WindowConfig: []WindowConfig{{WindowName: "nvim", Cmd: stringPtr("nvim README.md")}},
+				},
+			},
+			item: &dataproviders.Item{Id: "unknown"},
+			expected: ProjectConfig{
+				WindowConfig: []WindowConfig{
+					{WindowName: "nvim", Cmd: stringPtr("nvim README.md"), Primary: boolPtr(true)},
+					{WindowName: "shell"},
+				},
+				Env:      map[string]string{"EDITOR": "nvim", "STAGE": "dev"},
+				OnCreate: "make deps",
+			},
+		},
+		{
+			name: "worktree resolves the parent project",
+			config: &Config{
+				Template: []ProjectConfig{base},
+				Project:  []ProjectConfig{{Name: stringPtr("api"), Extends: stringPtr("base"), RemoveWindows: []string{"nvim", "git", "perf"}}},
+			},
+			item: &dataproviders.Item{Id: "feature", ParentId: "api"},
+			expected: ProjectConfig{
+				Name:         stringPtr("feature"),
+				WindowConfig: []WindowConfig{{WindowName: "shell"}},
+				Env:          map[string]string{"EDITOR": "nvim", "STAGE": "dev"},
+				OnCreate:     "direnv allow",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.config.GetProjectConfig(tt.item)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestGetProjectConfig_ExtendsDoesNotModifyParent(t *testing.T) {
+	config := &Config{
+		Template: []ProjectConfig{{
+			Name:         stringPtr("base"),
+			WindowConfig: []WindowConfig{{WindowName: "nvim", Primary: boolPtr(true)}},
+			Env:          map[string]string{"STAGE": "dev"},
+		}},
+		Project: []ProjectConfig{{
+			Name:         stringPtr("api"),
+			Extends:      stringPtr("base"),
+			WindowConfig: []WindowConfig{{WindowName: "nvim", Cmd: stringPtr("nvim .")}, {WindowName: "shell", Primary: boolPtr(true)}},
+			Env:          map[string]string{"STAGE": "test"},
+		}},
+	}
+
+	_, err := config.GetProjectConfig(&dataproviders.Item{Id: "api"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []WindowConfig{{WindowName: "nvim", Primary: boolPtr(true)}}, config.Template[0].WindowConfig)
+	assert.Equal(t, map[string]string{"STAGE": "dev"}, config.Template[0].Env)
+}
+
+func TestGetProjectConfig_ExtendsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *Config
+		expected string
+	}{
+		{
+			name: "unknown parent",
+			config: &Config{
+				Project: []ProjectConfig{{Name: stringPtr("api"), Extends: stringPtr("missing")}},
+			},
+			expected: "cannot extend missing: no template or project with that name",
+		},
+		{
+			name: "project extends itself",
+			config: &Config{
+				Project: []ProjectConfig{{Name: stringPtr("api"), Extends: stringPtr("api")}},
+			},
+			expected: "extends cycle detected: api -> api",
+		},
+		{
+			name: "cycle through templates",
+			config: &Config{
+				Template: []ProjectConfig{
+					{Name: stringPtr("a"), Extends: stringPtr("b")},
+					{Name: stringPtr("b"), Extends: stringPtr("a")},
+				},
+				Project: []ProjectConfig{{Name: stringPtr("api"), Extends: stringPtr("a")}},
+			},
+			expected: "extends cycle detected: api -> a -> b -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.config.GetProjectConfig(&dataproviders.Item{Id: "api"})
+			require.Error(t, err)
+			assert.EqualError(t, err, tt.expected)
+
+			assert.Error(t, validateConfig(tt.config), "config-validate should report the error")
+		})
+	}
+}
+
+func TestValidateConfig_ExtendsPrimary(t *testing.T) {
+	config := &Config{
+		Template: []ProjectConfig{{Name: stringPtr("base"), WindowConfig: []WindowConfig{{WindowName: "nvim", Primary: boolPtr(true)}}}},
+		Project: []ProjectConfig{{
+			Name:         stringPtr("api"),
+			Extends:      stringPtr("base"),
+			WindowConfig: []WindowConfig{{WindowName: "shell", Primary: boolPtr(true)}},
+		}},
+	}
+
+	assert.NoError(t, validateConfig(config), "a primary window of the child replaces the inherited one")
+
+	config.Template = append(config.Template, ProjectConfig{})
+	assert.EqualError(t, validateConfig(config), "every template needs a name")
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
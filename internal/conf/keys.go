@@ -0,0 +1,139 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Actions of the picker, which the [keys] table binds to keys
+const (
+	KeyUp            = "up"
+	KeyDown          = "down"
+	KeyPageUp        = "page-up"
+	KeyPageDown      = "page-down"
+	KeySelect        = "select"
+	KeyMark          = "mark"
+	KeyMarkDown      = "mark-down"
+	KeyToggleFold    = "toggle-fold"
+	KeyCollapse      = "collapse"
+	KeyExpand        = "expand"
+	KeyPin           = "pin"
+	KeyKill          = "kill"
+	KeyRename        = "rename"
+	KeyDetach        = "detach"
+	KeyPrint         = "print"
+	KeyTogglePreview = "toggle-preview"
+	KeyPreviewUp     = "preview-up"
+	KeyPreviewDown   = "preview-down"
+	KeyQuit          = "quit"
+)
+
+// KeyActions are all actions of the picker, in the order of its help line
+var KeyActions = []string{
+	KeyUp, KeyDown, KeyPageUp, KeyPageDown, KeySelect, KeyMark, KeyMarkDown,
+	KeyToggleFold, KeyCollapse, KeyExpand, KeyPin, KeyKill, KeyRename,
+	KeyDetach, KeyPrint, KeyTogglePreview, KeyPreviewUp, KeyPreviewDown,
+	KeyQuit,
+}
+
+// DefaultKeys are the keys of the actions which the [keys] table leaves out
+var DefaultKeys = map[string][]string{
+	KeyUp:            {"up", "ctrl+p"},
+	KeyDown:          {"down", "ctrl+n"},
+	KeyPageUp:        {"shift+up"},
+	KeyPageDown:      {"shift+down"},
+	KeySelect:        {"enter"},
+	KeyMark:          {"tab"},
+	KeyMarkDown:      {"shift+tab"},
+	KeyToggleFold:    {"ctrl+o"},
+	KeyCollapse:      {"left"},
+	KeyExpand:        {"right"},
+	KeyPin:           {"ctrl+s"},
+	KeyKill:          {"ctrl+x"},
+	KeyRename:        {"ctrl+r"},
+	KeyDetach:        {"ctrl+t"},
+	KeyPrint:         {"ctrl+y"},
+	KeyTogglePreview: {"ctrl+g"},
+	KeyPreviewUp:     {"pgup"},
+	KeyPreviewDown:   {"pgdown"},
+	KeyQuit:          {"esc", "ctrl+c"},
+}
+
+// namedKeys are the names bubbletea gives to keys which are not characters
+var namedKeys = func() map[string]bool {
+	names := make(map[string]bool)
+	for k := tea.KeyF20; k <= tea.KeyCtrlQuestionMark; k++ {
+		if name := k.String(); name != "" && k != tea.KeyRunes && k != tea.KeySpace {
+			names[name] = true
+		}
+	}
+	return names
+}()
+
+// KeyBindings returns the keys of every action, the ones set in the [keys]
+// table or the default ones. An empty list of keys disables an action.
+func (c *Config) KeyBindings() map[string][]string {
+	bindings := make(map[string][]string, len(KeyActions))
+	for _, action := range KeyActions {
+		keys, ok := c.Keys[action]
+		if !ok {
+			keys = DefaultKeys[action]
+		}
+		bindings[action] = keys
+	}
+	return bindings
+}
+
+func validateKeys(conf *Config) error {
+	for _, action := range slices.Sorted(maps.Keys(conf.Keys)) {
+		keys := conf.Keys[action]
+		if !slices.Contains(KeyActions, action) {
+			return fmt.Errorf("invalid action %q in keys, expected one of %s", action, strings.Join(KeyActions, ", "))
+		}
+		for _, key := range keys {
+			if err := validateKey(key); err != nil {
+				return fmt.Errorf("invalid key %q for %s: %w", key, action, err)
+			}
+		}
+	}
+
+	bindings := conf.KeyBindings()
+	if len(bindings[KeyQuit]) == 0 {
+		return errors.New("quit needs a key in keys")
+	}
+
+	bound := make(map[string]string)
+	for _, action := range KeyActions {
+		for _, key := range bindings[action] {
+			if other, ok := bound[key]; ok {
+				return fmt.Errorf("key %q is bound to both %s and %s", key, other, action)
+			}
+			bound[key] = action
+		}
+	}
+
+	return nil
+}
+
+// validateKey checks that key is the name of a key as bubbletea reports it,
+// like "ctrl+k", "alt+j" or "pgdown"
+func validateKey(key string) error {
+	if namedKeys[key] {
+		return nil
+	}
+
+	rest, alt := strings.CutPrefix(key, "alt+")
+	if alt && (namedKeys[rest] || utf8.RuneCountInString(rest) == 1) {
+		return nil
+	}
+	if utf8.RuneCountInString(key) == 1 {
+		return errors.New("it would be typed into the search, add a modifier like alt+")
+	}
+	return errors.New("unknown key name")
+}
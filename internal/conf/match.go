@@ -0,0 +1,168 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/niedch/mux-session/internal/dataproviders"
+)
+
+const regexPrefix = "regex:"
+
+// ProjectMatch tells which config block applies to an item and why
+type ProjectMatch struct {
+	// Project is the matching block, or nil when the default applies
+	Project *ProjectConfig
+	// Pattern is the match pattern that selected Project, empty for an
+	// exact name match
+	Pattern string
+	Reason  string
+}
+
+// MatchProject finds the config block for the item. An exact name match wins
+// over match patterns, and of several matching patterns the most specific one
+// wins. Without a match the default applies.
+func (c *Config) MatchProject(item *dataproviders.Item) ProjectMatch {
+	configId := item.Id
+	if item.ParentId != "" {
+		configId = item.ParentId
+	}
+
+	if project := c.findProject(configId); project != nil {
+		return ProjectMatch{
+			Project: project,
+			Reason:  fmt.Sprintf("project %s matches %s by name", *project.Name, configId),
+		}
+	}
+
+	var best *ProjectConfig
+	bestSpecificity := -1
+	candidates := 0
+	for i := range c.Project {
+		project := &c.Project[i]
+		if project.Match == "" {
+			continue
+		}
+
+		if _, ok := matchItem(project.Match, configId, item); !ok {
+			continue
+		}
+
+		candidates++
+		// Ties keep the block which comes first in the config
+		if specificity := patternSpecificity(project.Match); specificity > bestSpecificity {
+			best = project
+			bestSpecificity = specificity
+		}
+	}
+
+	if best != nil {
+		subject, _ := matchItem(best.Match, configId, item)
+		return ProjectMatch{
+			Project: best,
+			Pattern: best.Match,
+			Reason: fmt.Sprintf("%s matches %s (most specific of %d matching patterns)",
+				describeProject(best), subject, candidates),
+		}
+	}
+
+	return ProjectMatch{
+		Reason: fmt.Sprintf("no project name or match pattern matches %s, the default applies", configId),
+	}
+}
+
+func describeProject(project *ProjectConfig) string {
+	if project.Name != nil {
+		return fmt.Sprintf("project %s with match %q", *project.Name, project.Match)
+	}
+	return fmt.Sprintf("project with match %q", project.Match)
+}
+
+// matchItem matches the pattern against the item and describes what matched.
+// Path patterns of a worktree are tried on its own path and on the path of its
+// repository, so that a worktree outside of the repository gets its config.
+func matchItem(pattern string, name string, item *dataproviders.Item) (string, bool) {
+	if !isPathPattern(pattern) {
+		// Ids of nested directories are paths below the search path
+		name = path.Base(name)
+		ok, err := matchPattern(pattern, name)
+		return "directory name " + name, err == nil && ok
+	}
+
+	for _, dir := range []string{item.Path, item.ParentPath} {
+		if dir == "" {
+			continue
+		}
+		if ok, err := matchPattern(pattern, dir); err == nil && ok {
+			return "path " + dir, true
+		}
+	}
+	return "", false
+}
+
+// isPathPattern tells whether a pattern is matched against the full path
+// instead of the directory name, which is the case when it contains a slash
+func isPathPattern(pattern string) bool {
+	return strings.Contains(strings.TrimPrefix(pattern, regexPrefix), "/")
+}
+
+// matchPattern matches a glob, or a regular expression prefixed with
+// "regex:", against the directory name or the path
+func matchPattern(pattern string, subject string) (bool, error) {
+	if expr, ok := strings.CutPrefix(pattern, regexPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(subject), nil
+	}
+
+	return filepath.Match(expandHome(pattern), subject)
+}
+
+func validatePattern(pattern string) error {
+	if expr, ok := strings.CutPrefix(pattern, regexPrefix); ok {
+		_, err := regexp.Compile(expr)
+		return err
+	}
+
+	_, err := filepath.Match(pattern, "")
+	return err
+}
+
+// patternSpecificity counts the literal characters of a pattern, so that
+// "acme-*-service" is more specific than "*-service"
+func patternSpecificity(pattern string) int {
+	meta := "*?[]\\"
+	if expr, ok := strings.CutPrefix(pattern, regexPrefix); ok {
+		pattern = expr
+		meta = `\.+*?()|[]{}^$`
+	} else {
+		pattern = expandHome(pattern)
+	}
+
+	specificity := 0
+	for _, r := range pattern {
+		if !strings.ContainsRune(meta, r) {
+			specificity++
+		}
+	}
+	return specificity
+}
+
+func expandHome(pattern string) string {
+	rest, ok := strings.CutPrefix(pattern, "~/")
+	if !ok {
+		return pattern
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return pattern
+	}
+	return filepath.Join(home, rest)
+}
@@ -0,0 +1,139 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchProject(t *testing.T) {
+	t.Setenv("HOME", "/home/user")
+
+	config := &Config{
+		Project: []ProjectConfig{
+			{Match: "*-service", Env: map[string]string{"BLOCK": "service"}},
+			{Name: stringPtr("billing-service"), Env: map[string]string{"BLOCK": "billing"}},
+			{Match: "acme-*-service", Env: map[string]string{"BLOCK": "acme"}},
+			{Match: "~/work/clients/*", Env: map[string]string{"BLOCK": "clients"}},
+			{Match: "regex:^(api|web)-v[0-9]+$", Env: map[string]string{"BLOCK": "versioned"}},
+			{Match: "*-serv?ce", Env: map[string]string{"BLOCK": "tie"}},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		item          *dataproviders.Item
+		expectedBlock string
+		expectedWhy   string
+	}{
+		{
+			name:          "exact name wins over patterns",
+			item:          &dataproviders.Item{Id: "billing-service", Path: "/src/billing-service"},
+			expectedBlock: "billing",
+			expectedWhy:   "project billing-service matches billing-service by name",
+		},
+		{
+			name:          "glob on the directory name",
+			item:          &dataproviders.Item{Id: "users-service", Path: "/src/users-service"},
+			expectedBlock: "service",
+			expectedWhy:   `project with match "*-service" matches directory name users-service (most specific of 2 matching patterns)`,
+		},
+		{
+			name:          "most specific pattern wins",
+			item:          &dataproviders.Item{Id: "acme-users-service", Path: "/src/acme-users-service"},
+			expectedBlock: "acme",
+			expectedWhy:   `project with match "acme-*-service" matches directory name acme-users-service (most specific of 3 matching patterns)`,
+		},
+		{
+			name:          "path glob with home directory",
+			item:          &dataproviders.Item{Id: "initech", Path: "/home/user/work/clients/initech"},
+			expectedBlock: "clients",
+		},
+		{
+			name:          "path glob does not match nested directories",
+			item:          &dataproviders.Item{Id: "initech", Path: "/home/user/work/clients/initech/app"},
+			expectedBlock: "",
+			expectedWhy:   "no project name or match pattern matches initech, the default applies",
+		},
+		{
+			name:          "glob on the directory name of a nested directory",
+			item:          &dataproviders.Item{Id: "acme/orders-service", Path: "/src/acme/orders-service"},
+			expectedBlock: "service",
+			expectedWhy:   `project with match "*-service" matches directory name orders-service (most specific of 2 matching patterns)`,
+		},
+		{
+			name:          "regex on the directory name",
+			item:          &dataproviders.Item{Id: "api-v2", Path: "/src/api-v2"},
+			expectedBlock: "versioned",
+		},
+		{
+			name:          "worktree matches through its parent",
+			item:          &dataproviders.Item{Id: "feature", ParentId: "orders-service", Path: "/src/orders-service/feature"},
+			expectedBlock: "service",
+		},
+		{
+			name:          "worktree matches the path of its parent",
+			item:          &dataproviders.Item{Id: "hotfix", ParentId: "initech", Path: "/home/user/wt/hotfix", ParentPath: "/home/user/work/clients/initech"},
+			expectedBlock: "clients",
+			expectedWhy:   `project with match "~/work/clients/*" matches path /home/user/work/clients/initech (most specific of 1 matching patterns)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := config.MatchProject(tt.item)
+
+			if tt.expectedBlock == "" {
+				assert.Nil(t, match.Project)
+			} else {
+				require.NotNil(t, match.Project)
+				assert.Equal(t, tt.expectedBlock, match.Project.Env["BLOCK"])
+			}
+			if tt.expectedWhy != "" {
+				assert.Equal(t, tt.expectedWhy, match.Reason)
+			}
+		})
+	}
+}
+
+func TestGetProjectConfig_MatchKeepsSessionName(t *testing.T) {
+	config := &Config{
+		Template: []ProjectConfig{{Name: stringPtr("service"), WindowConfig: []WindowConfig{{WindowName: "nvim"}}}},
+		Project: []ProjectConfig{
+			{Name: stringPtr("services"), Match: "*-service", Extends: stringPtr("service")},
+		},
+	}
+
+	result, err := config.GetProjectConfig(&dataproviders.Item{Id: "users-service", Path: "/src/users-service"})
+	require.NoError(t, err)
+
+	assert.Nil(t, result.Name, "the session should be named after the directory")
+	assert.Equal(t, []WindowConfig{{WindowName: "nvim"}}, result.WindowConfig)
+}
+
+func TestValidateConfig_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		project  ProjectConfig
+		expected string
+	}{
+		{name: "invalid regex", project: ProjectConfig{Match: "regex:(api"}, expected: `invalid match pattern "regex:(api"`},
+		{name: "invalid glob", project: ProjectConfig{Match: "[api"}, expected: `invalid match pattern "[api"`},
+		{name: "no name and no match", project: ProjectConfig{}, expected: "every project needs a name or a match pattern"},
+		{name: "valid pattern", project: ProjectConfig{Match: "regex:-service$"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfig(&Config{Project: []ProjectConfig{tt.project}})
+			if tt.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
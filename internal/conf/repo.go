@@ -0,0 +1,99 @@
+package conf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/knadh/koanf/parsers/toml"
+	"github.com/knadh/koanf/v2"
+)
+
+// RepoConfigFiles are the paths, relative to the project root, where a
+// project can carry its own config
+var RepoConfigFiles = []string{
+	".mux-session.toml",
+	filepath.Join(".mux-session", "config.toml"),
+}
+
+// RepoConfig is a project config checked into the project itself
+type RepoConfig struct {
+	// File is the absolute path of the config file
+	File string
+	// Hash is the sha256 of the file content, used to trust the file
+	Hash    string
+	Project ProjectConfig
+}
+
+// FindRepoConfig loads the repo config of the project in dir. It returns nil
+// if the project has none.
+func FindRepoConfig(dir string) (*RepoConfig, error) {
+	for _, name := range RepoConfigFiles {
+		file, err := filepath.Abs(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return parseRepoConfig(file, content)
+	}
+
+	return nil, nil
+}
+
+func parseRepoConfig(file string, content []byte) (*RepoConfig, error) {
+	k := koanf.New(".")
+	if err := k.Load(bytesProvider(content), toml.Parser()); err != nil {
+		return nil, fmt.Errorf("cannot load repo config from '%s': %w", file, err)
+	}
+
+	var project ProjectConfig
+	if err := k.UnmarshalWithConf("", &project, koanf.UnmarshalConf{Tag: "koanf"}); err != nil {
+		return nil, fmt.Errorf("cannot load repo config from '%s': %w", file, err)
+	}
+
+	if project.Name != nil || project.Match != "" || project.Extends != nil {
+		return nil, fmt.Errorf("repo config '%s' cannot set name, match or extends", file)
+	}
+
+	if err := validateProjectConfig(project); err != nil {
+		return nil, fmt.Errorf("repo config '%s' is invalid: %w", file, err)
+	}
+
+	sum := sha256.Sum256(content)
+	return &RepoConfig{
+		File:    file,
+		Hash:    hex.EncodeToString(sum[:]),
+		Project: project,
+	}, nil
+}
+
+// MergeRepoConfig lays the repo config over the project config from the
+// global config, in the same way extends does
+func MergeRepoConfig(project ProjectConfig, repo *RepoConfig) ProjectConfig {
+	result := mergeProjectConfig(project, repo.Project)
+	result.Name = project.Name
+
+	return result
+}
+
+// bytesProvider serves an already read config file to koanf
+type bytesProvider []byte
+
+func (b bytesProvider) ReadBytes() ([]byte, error) {
+	return b, nil
+}
+
+func (b bytesProvider) Read() (map[string]any, error) {
+	return nil, errors.New("bytesProvider does not support Read")
+}
@@ -0,0 +1,104 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestFindRepoConfig(t *testing.T) {
+	t.Run("no repo config", func(t *testing.T) {
+		repo, err := FindRepoConfig(t.TempDir())
+		require.NoError(t, err)
+		assert.Nil(t, repo)
+	})
+
+	t.Run("dot file", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, ".mux-session.toml"), `
+on_create = "make deps"
+
+[env]
+STAGE = "dev"
+
+[[window]]
+window_name = "server"
+cmd = "make run"
+`)
+
+		repo, err := FindRepoConfig(dir)
+		require.NoError(t, err)
+		require.NotNil(t, repo)
+
+		assert.Equal(t, filepath.Join(dir, ".mux-session.toml"), repo.File)
+		assert.Len(t, repo.Hash, 64)
+		assert.Equal(t, ProjectConfig{
+			WindowConfig: []WindowConfig{{WindowName: "server", Cmd: stringPtr("make run")}},
+			Env:          map[string]string{"STAGE": "dev"},
+			OnCreate:     "make deps",
+		}, repo.Project)
+	})
+
+	t.Run("config directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, ".mux-session", "config.toml"), `
+[[window]]
+window_name = "server"
+`)
+
+		repo, err := FindRepoConfig(dir)
+		require.NoError(t, err)
+		require.NotNil(t, repo)
+		assert.Equal(t, filepath.Join(dir, ".mux-session", "config.toml"), repo.File)
+	})
+
+	t.Run("name cannot be set", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, ".mux-session.toml"), `name = "other"`)
+
+		_, err := FindRepoConfig(dir)
+		assert.ErrorContains(t, err, "cannot set name, match or extends")
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, ".mux-session.toml"), `
+[[window]]
+window_name = "perf"
+
+[[window.panel_config]]
+panel_direction = "x"
+`)
+
+		_, err := FindRepoConfig(dir)
+		assert.ErrorContains(t, err, "panel_direction must be 'v' or 'h'")
+	})
+}
+
+func TestMergeRepoConfig(t *testing.T) {
+	project := ProjectConfig{
+		Name:         stringPtr("api"),
+		WindowConfig: []WindowConfig{{WindowName: "nvim", Cmd: stringPtr("nvim .")}, {WindowName: "git"}},
+		Env:          map[string]string{"EDITOR": "nvim"},
+	}
+	repo := &RepoConfig{Project: ProjectConfig{
+		RemoveWindows: []string{"git"},
+		WindowConfig:  []WindowConfig{{WindowName: "server", Cmd: stringPtr("make run")}},
+		Env:           map[string]string{"STAGE": "dev"},
+	}}
+
+	assert.Equal(t, ProjectConfig{
+		Name:         stringPtr("api"),
+		WindowConfig: []WindowConfig{{WindowName: "nvim", Cmd: stringPtr("nvim .")}, {WindowName: "server", Cmd: stringPtr("make run")}},
+		Env:          map[string]string{"EDITOR": "nvim", "STAGE": "dev"},
+	}, MergeRepoConfig(project, repo))
+}
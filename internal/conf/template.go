@@ -0,0 +1,79 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/niedch/mux-session/internal/dataproviders"
+)
+
+// TemplateData is the data available to window and panel commands, e.g.
+// `cmd = "nvim {{.Path}}"`
+type TemplateData struct {
+	// Name is the tmux session name
+	Name     string
+	Path     string
+	ParentId string
+	// IsWorktree mirrors Item.IsWorktree, which is set when the project
+	// has git worktrees
+	IsWorktree bool
+	Env        map[string]string
+}
+
+// NewTemplateData describes the selected item for a session called name. Env
+// holds the process environment overlaid with the project env.
+func NewTemplateData(item *dataproviders.Item, name string, env map[string]string) TemplateData {
+	data := TemplateData{
+		Name:       name,
+		Path:       item.Path,
+		ParentId:   item.ParentId,
+		IsWorktree: item.IsWorktree,
+		Env:        make(map[string]string),
+	}
+
+	for _, entry := range os.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			data.Env[key] = value
+		}
+	}
+	for key, value := range env {
+		data.Env[key] = value
+	}
+
+	return data
+}
+
+// Branch is the checked out git branch of the project. It is resolved only
+// when a command uses it and is empty outside of a git repository.
+func (d TemplateData) Branch() string {
+	output, err := exec.Command("git", "-C", d.Path, "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func parseCommand(cmd string) (*template.Template, error) {
+	return template.New("cmd").Option("missingkey=zero").Parse(cmd)
+}
+
+// ExpandCommand renders the template variables of a window or panel command
+func ExpandCommand(cmd string, data TemplateData) (string, error) {
+	if !strings.Contains(cmd, "{{") {
+		return cmd, nil
+	}
+
+	tmpl, err := parseCommand(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to expand command %q: %w", cmd, err)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,88 @@
+package conf
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandCommand(t *testing.T) {
+	t.Setenv("MUX_TEMPLATE_TEST", "from-process")
+	item := &dataproviders.Item{Id: "feature", Path: "/home/user/project/feature", ParentId: "project"}
+	data := NewTemplateData(item, "feature", map[string]string{"FOO": "bar"})
+
+	tests := []struct {
+		name     string
+		cmd      string
+		expected string
+	}{
+		{name: "plain command is unchanged", cmd: "vim .", expected: "vim ."},
+		{name: "item fields", cmd: "echo {{.Name}} {{.Path}} {{.ParentId}}", expected: "echo feature /home/user/project/feature project"},
+		{name: "conditional", cmd: "{{if .ParentId}}git status{{else}}ls{{end}}", expected: "git status"},
+		{name: "project env", cmd: "echo {{.Env.FOO}}", expected: "echo bar"},
+		{name: "process env", cmd: "echo {{.Env.MUX_TEMPLATE_TEST}}", expected: "echo from-process"},
+		{name: "missing env is empty", cmd: "echo {{.Env.MUX_TEMPLATE_MISSING}}", expected: "echo "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded, err := ExpandCommand(tt.cmd, data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, expanded)
+		})
+	}
+}
+
+func TestExpandCommand_Branch(t *testing.T) {
+	dir := t.TempDir()
+	out, err := exec.Command("git", "-C", dir, "init", "-q", "-b", "feature/templates").CombinedOutput()
+	require.NoError(t, err, string(out))
+	out, err = exec.Command("git", "-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "init").CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	expanded, err := ExpandCommand("git log {{.Branch}}", NewTemplateData(&dataproviders.Item{Path: dir}, "project", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "git log feature/templates", expanded)
+
+	expanded, err = ExpandCommand("echo {{.Branch}}", NewTemplateData(&dataproviders.Item{Path: t.TempDir()}, "project", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "echo ", expanded, "branch is empty outside of a git repository")
+}
+
+func TestValidateCommandTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		window   WindowConfig
+		expected string
+	}{
+		{
+			name:     "invalid window cmd",
+			window:   WindowConfig{WindowName: "vim", Cmd: stringPtr("vim {{.Path")},
+			expected: "invalid cmd template in window vim",
+		},
+		{
+			name:     "invalid panel cmd",
+			window:   WindowConfig{WindowName: "perf", PanelConfig: []PanelConfig{{PanelDirection: "h"}, {PanelDirection: "v", Cmd: "{{if .Name}}top"}}},
+			expected: "invalid cmd template in panel 1 of window perf",
+		},
+		{
+			name:   "valid templates",
+			window: WindowConfig{WindowName: "vim", Cmd: stringPtr("cd {{.Path}} && vim"), PanelConfig: []PanelConfig{{PanelDirection: "h", Cmd: "echo {{.Env.HOME}}"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfig(&Config{Project: []ProjectConfig{{Name: stringPtr("project"), WindowConfig: []WindowConfig{tt.window}}}})
+			if tt.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
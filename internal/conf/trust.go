@@ -0,0 +1,105 @@
+package conf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/adrg/xdg"
+)
+
+// TrustStore is the allowlist of repo config files which may be applied. An
+// entry holds the hash of the file content, so editing a trusted file
+// requires trusting it again.
+type TrustStore struct {
+	path string
+}
+
+func NewTrustStore(path string) *TrustStore {
+	return &TrustStore{path: path}
+}
+
+// DefaultTrustStore keeps the allowlist in XDG_DATA_HOME/mux-session/trusted
+func DefaultTrustStore() (*TrustStore, error) {
+	path, err := xdg.DataFile(filepath.Join("mux-session", "trusted"))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTrustStore(path), nil
+}
+
+// IsTrusted tells whether the repo config was trusted with its current content
+func (s *TrustStore) IsTrusted(repo *RepoConfig) (bool, error) {
+	entries, err := s.read()
+	if err != nil {
+		return false, err
+	}
+
+	return entries[repo.File] == repo.Hash, nil
+}
+
+// Trust adds the repo config with its current content to the allowlist
+func (s *TrustStore) Trust(repo *RepoConfig) error {
+	entries, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	entries[repo.File] = repo.Hash
+	return s.write(entries)
+}
+
+// Revoke removes the repo config from the allowlist
+func (s *TrustStore) Revoke(repo *RepoConfig) error {
+	entries, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	delete(entries, repo.File)
+	return s.write(entries)
+}
+
+// read parses the allowlist, which has one "<hash> <file>" entry per line
+func (s *TrustStore) read() (map[string]string, error) {
+	entries := make(map[string]string)
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hash, path, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		entries[path] = hash
+	}
+
+	return entries, scanner.Err()
+}
+
+func (s *TrustStore) write(entries map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	for _, path := range slices.Sorted(maps.Keys(entries)) {
+		fmt.Fprintf(&content, "%s %s\n", entries[path], path)
+	}
+
+	return os.WriteFile(s.path, []byte(content.String()), 0o600)
+}
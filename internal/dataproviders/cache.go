@@ -0,0 +1,322 @@
+package dataproviders
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/adrg/xdg"
+	"github.com/niedch/mux-session/internal/logger"
+)
+
+// cacheVersion is part of the cache key, so a cache written by a version with
+// a different item layout is not used
+const cacheVersion = 4
+
+// ItemCache stores the items of a DirectoryProvider on disk
+type ItemCache struct {
+	path string
+}
+
+func NewItemCache(path string) *ItemCache {
+	return &ItemCache{path: path}
+}
+
+// DefaultItemCache keeps the items in XDG_CACHE_HOME/mux-session/items.json
+func DefaultItemCache() (*ItemCache, error) {
+	path, err := xdg.CacheFile(filepath.Join("mux-session", "items.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewItemCache(path), nil
+}
+
+// Path is the file the items are stored in
+func (c *ItemCache) Path() string {
+	return c.path
+}
+
+// Clear removes the cached items
+func (c *ItemCache) Clear() error {
+	err := os.Remove(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// cacheEntry is the content of the cache file. Items are valid for the search
+// paths hashed into Key as long as no directory in Mtimes changed.
+type cacheEntry struct {
+	Key    string           `json:"key"`
+	Mtimes map[string]int64 `json:"mtimes"`
+	Items  []Item           `json:"items"`
+}
+
+func (c *ItemCache) load() (*cacheEntry, error) {
+	content, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+func (c *ItemCache) save(entry *cacheEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so a concurrent reader never sees a
+	// partially written cache
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".items-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// isFresh tells whether none of the scanned directories changed since the
+// entry was written
+func (e *cacheEntry) isFresh() bool {
+	for path, mtime := range e.Mtimes {
+		if modTime(path) != mtime {
+			return false
+		}
+	}
+
+	return true
+}
+
+// headMtimes returns the modification times of the HEAD files of the
+// repository in dir and of its worktrees, which the branches of its items are
+// read from. Directories which are no repository have none.
+func headMtimes(dir string) map[string]int64 {
+	heads := make(map[string]int64)
+	if head := gitHead(dir); head != "" {
+		if mtime := modTime(head); mtime != 0 {
+			heads[head] = mtime
+		}
+	}
+
+	worktreeHeads, _ := filepath.Glob(filepath.Join(dir, ".git", "worktrees", "*", "HEAD"))
+	for _, head := range worktreeHeads {
+		heads[head] = modTime(head)
+	}
+
+	return heads
+}
+
+// modTime is the modification time of path, or 0 if it does not exist
+func modTime(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// CachedProvider serves the items of a DirectoryProvider from an ItemCache.
+// The cached items are streamed right away, and if any scanned directory
+// changed since, the directories are scanned again in the background and the
+// cache is updated once the scan completes. An item of the new scan is sent
+// again with the Id and Path of the cached item it replaces, and cached items
+// the scan did not find are sent again as Removed.
+type CachedProvider struct {
+	provider *DirectoryProvider
+	cache    *ItemCache
+}
+
+func NewCachedProvider(provider *DirectoryProvider, cache *ItemCache) *CachedProvider {
+	return &CachedProvider{
+		provider: provider,
+		cache:    cache,
+	}
+}
+
+// GetItems returns the items of a fresh cache, or scans the directories
+func (cp *CachedProvider) GetItems() ([]Item, error) {
+	var items []Item
+	index := make(map[itemKey]int)
+	stream, errs := cp.StreamItems(context.Background())
+	for item := range stream {
+		if i, ok := index[keyOf(item)]; ok {
+			items[i] = item
+			continue
+		}
+		index[keyOf(item)] = len(items)
+		items = append(items, item)
+	}
+	items = slices.DeleteFunc(items, func(item Item) bool { return item.Removed })
+
+	return items, <-errs
+}
+
+// StreamItems sends the cached items and the changed items of a refresh. A
+// broken cache only falls back to scanning, so the stream does not fail.
+func (cp *CachedProvider) StreamItems(ctx context.Context) (<-chan Item, <-chan error) {
+	out := make(chan Item)
+
+	go func() {
+		defer close(out)
+
+		key := cp.key()
+		entry, err := cp.cache.load()
+		if err != nil {
+			logger.Printf("Ignoring item cache %s: %v\n", cp.cache.Path(), err)
+			entry = nil
+		}
+		if entry != nil && entry.Key != key {
+			entry = nil
+		}
+
+		sent := make(map[itemKey]Item)
+		if entry != nil {
+			fresh := entry.isFresh()
+			for _, item := range entry.Items {
+				// Directories removed since the cache was written are dropped
+				// without waiting for the scan
+				if !fresh && modTime(item.Path) == 0 {
+					continue
+				}
+				if !sendItem(ctx, out, item) {
+					return
+				}
+				sent[keyOf(item)] = item
+			}
+
+			if fresh {
+				logger.Printf("Using %d cached items from %s\n", len(entry.Items), cp.cache.Path())
+				return
+			}
+		}
+
+		logger.Printf("Refreshing item cache %s\n", cp.cache.Path())
+		cp.refresh(ctx, key, out, sent)
+	}()
+
+	return out, noErrors()
+}
+
+// refresh scans the directories, sends the items which are not among the
+// sent ones or differ from them, takes back the sent ones which are gone and
+// saves the result
+func (cp *CachedProvider) refresh(ctx context.Context, key string, out chan<- Item, sent map[itemKey]Item) {
+	var mu sync.Mutex
+	mtimes := make(map[string]int64)
+	onVisit := func(dir string) {
+		dirMtime := modTime(dir)
+		worktreesMtime := modTime(filepath.Join(dir, ".git", "worktrees"))
+		heads := headMtimes(dir)
+
+		mu.Lock()
+		defer mu.Unlock()
+		mtimes[dir] = dirMtime
+		// Adding a worktree does not touch the project directory itself
+		mtimes[filepath.Join(dir, ".git", "worktrees")] = worktreesMtime
+		// Neither does checking out another branch
+		maps.Copy(mtimes, heads)
+	}
+
+	var items []Item
+	scanned := make(map[itemKey]bool)
+	for item := range cp.provider.stream(ctx, onVisit) {
+		items = append(items, item)
+		scanned[keyOf(item)] = true
+
+		if previous, ok := sent[keyOf(item)]; ok && itemsEqual(previous, item) {
+			continue
+		}
+		if !sendItem(ctx, out, item) {
+			return
+		}
+	}
+
+	// An incomplete scan must not replace the cache
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Cached directories which are no longer projects, e.g. as their marker
+	// was removed, still exist and were sent
+	for key, item := range sent {
+		if scanned[key] {
+			continue
+		}
+		item.Removed = true
+		if !sendItem(ctx, out, item) {
+			return
+		}
+	}
+
+	if err := cp.cache.save(&cacheEntry{Key: key, Mtimes: mtimes, Items: items}); err != nil {
+		logger.Printf("Failed to save item cache %s: %v\n", cp.cache.Path(), err)
+	}
+}
+
+// key identifies the search path config the items were scanned with
+func (cp *CachedProvider) key() string {
+	content, _ := json.Marshal(struct {
+		Version     int
+		SearchPaths []SearchPath
+	}{cacheVersion, cp.provider.searchPaths})
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// itemKey identifies an item across scans
+type itemKey struct {
+	id   string
+	path string
+}
+
+func keyOf(item Item) itemKey {
+	return itemKey{id: item.Id, path: item.Path}
+}
+
+func itemsEqual(a, b Item) bool {
+	left, _ := json.Marshal(a)
+	right, _ := json.Marshal(b)
+	return string(left) == string(right)
+}
+
+func sendItem(ctx context.Context, out chan<- Item, item Item) bool {
+	select {
+	case out <- item:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
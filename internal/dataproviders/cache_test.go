@@ -0,0 +1,162 @@
+package dataproviders
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamItems collects the streamed items and checks the stream did not fail
+func streamItems(t *testing.T, provider StreamingProvider) []Item {
+	t.Helper()
+	stream, errs := provider.StreamItems(context.Background())
+	var items []Item
+	for item := range stream {
+		items = append(items, item)
+	}
+	require.NoError(t, <-errs)
+	return items
+}
+
+func streamPaths(t *testing.T, provider StreamingProvider, root string) []string {
+	t.Helper()
+	return itemPaths(streamItems(t, provider), root)
+}
+
+func TestCachedProvider_StreamItems(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, "alpha", "beta")
+
+	cache := NewItemCache(filepath.Join(t.TempDir(), "items.json"))
+	provider := NewCachedProvider(NewDirectoryProvider([]SearchPath{{Path: root}}), cache)
+
+	// Without a cache the directories are scanned and cached
+	assert.Equal(t, []string{"alpha", "beta"}, streamPaths(t, provider, root))
+	require.FileExists(t, cache.Path())
+
+	t.Run("fresh cache is used without scanning", func(t *testing.T) {
+		entry, err := cache.load()
+		require.NoError(t, err)
+		entry.Items = append(entry.Items, Item{Id: "cached-only", Path: filepath.Join(root, "cached-only")})
+		require.NoError(t, cache.save(entry))
+
+		assert.Equal(t, []string{"alpha", "beta", "cached-only"}, streamPaths(t, provider, root))
+	})
+
+	t.Run("stale cache is sent first and refreshed", func(t *testing.T) {
+		makeTree(t, root, "gamma")
+		require.NoError(t, os.Remove(filepath.Join(root, "beta")))
+
+		// Cached directories which no longer exist are dropped before the scan
+		assert.Equal(t, []string{"alpha", "gamma"}, streamPaths(t, provider, root))
+
+		entry, err := cache.load()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alpha", "gamma"}, itemPaths(entry.Items, root))
+	})
+
+	t.Run("changed items are sent again", func(t *testing.T) {
+		makeTree(t, root, "alpha/.git/worktrees/feature")
+		require.NoError(t, os.WriteFile(filepath.Join(root, "alpha/.git/worktrees/feature/gitdir"), []byte("/src/feature/.git"), 0o644))
+
+		items := streamItems(t, provider)
+		require.Len(t, items, 3)
+		assert.Equal(t, "alpha", items[2].Id)
+		assert.True(t, items[2].IsWorktree)
+	})
+
+	t.Run("cache of other search paths is ignored", func(t *testing.T) {
+		other := NewCachedProvider(NewDirectoryProvider([]SearchPath{{Path: root, MaxDepth: 2}}), cache)
+		assert.Equal(t, []string{"alpha", "gamma"}, streamPaths(t, other, root))
+	})
+}
+
+func TestCachedProvider_GetItemsReplacesChangedItems(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, "alpha", "beta")
+
+	cache := NewItemCache(filepath.Join(t.TempDir(), "items.json"))
+	provider := NewCachedProvider(NewDirectoryProvider([]SearchPath{{Path: root}}), cache)
+	_, err := provider.GetItems()
+	require.NoError(t, err)
+
+	makeTree(t, root, "beta/.git/worktrees")
+
+	items, err := provider.GetItems()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha", "beta"}, itemPaths(items, root))
+	assert.True(t, items[1].IsWorktree)
+}
+
+func TestCachedProvider_TakesBackItemsGoneFromTheScan(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, "alpha/.git", "beta/.git")
+
+	cache := NewItemCache(filepath.Join(t.TempDir(), "items.json"))
+	provider := NewCachedProvider(NewDirectoryProvider([]SearchPath{{Path: root, Markers: []string{".git"}}}), cache)
+	assert.Equal(t, []string{"alpha", "beta"}, streamPaths(t, provider, root))
+
+	// beta still exists, but is no longer a project
+	require.NoError(t, os.Remove(filepath.Join(root, "beta", ".git")))
+
+	items := streamItems(t, provider)
+	require.Len(t, items, 3)
+	assert.Equal(t, []string{"alpha", "beta", "beta"}, itemPaths(items, root))
+	assert.True(t, items[2].Removed, "the cached beta should be taken back after the scan")
+
+	entry, err := cache.load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha"}, itemPaths(entry.Items, root))
+
+	// A stale cache again, which GetItems resolves to the scanned items
+	makeTree(t, root, "gamma/.git")
+	require.NoError(t, os.Remove(filepath.Join(root, "alpha", ".git")))
+	all, err := provider.GetItems()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gamma"}, itemPaths(all, root))
+}
+
+func TestCachedProvider_RefreshesCheckedOutBranches(t *testing.T) {
+	root, worktrees := t.TempDir(), t.TempDir()
+	repo := filepath.Join(root, "api", ".git")
+	writeFile(t, filepath.Join(repo, "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(repo, "worktrees", "fix", "HEAD"), "ref: refs/heads/fix\n")
+	writeFile(t, filepath.Join(repo, "worktrees", "fix", "gitdir"), filepath.Join(worktrees, "fix", ".git"))
+	writeFile(t, filepath.Join(worktrees, "fix", ".git"), "gitdir: "+filepath.Join(repo, "worktrees", "fix")+"\n")
+
+	cache := NewItemCache(filepath.Join(t.TempDir(), "items.json"))
+	provider := NewCachedProvider(NewDirectoryProvider([]SearchPath{{Path: root, Markers: []string{".git"}}}), cache)
+	_, err := provider.GetItems()
+	require.NoError(t, err)
+
+	// Checking out a branch only rewrites HEAD, the mtime is set explicitly as
+	// the rewrite may fall into the same tick as the scan
+	checkout := func(head, branch string) {
+		writeFile(t, head, "ref: refs/heads/"+branch+"\n")
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(head, later, later))
+	}
+	checkout(filepath.Join(repo, "HEAD"), "develop")
+	checkout(filepath.Join(repo, "worktrees", "fix", "HEAD"), "fix-2")
+
+	items, err := provider.GetItems()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, []string{"develop"}, items[0].Metadata[MetadataBranch])
+	require.Len(t, items[0].SubItems, 1)
+	assert.Equal(t, []string{"fix-2"}, items[0].SubItems[0].Metadata[MetadataBranch])
+}
+
+func TestItemCache_Clear(t *testing.T) {
+	cache := NewItemCache(filepath.Join(t.TempDir(), "items.json"))
+	require.NoError(t, cache.Clear(), "clearing a missing cache is not an error")
+
+	require.NoError(t, cache.save(&cacheEntry{Key: "key"}))
+	require.NoError(t, cache.Clear())
+	assert.NoFileExists(t, cache.Path())
+}
@@ -1,10 +1,13 @@
 package dataproviders
 
+import "context"
+
 const (
 	SELECTED_ICON   = ""
 	UNSELECTED_ICON = "󰄱"
 	WORKTREE_ICON   = "󰙅"
 	TMUX_ICON       = ""
+	PINNED_ICON     = "󰐃"
 )
 
 type Item struct {
@@ -15,8 +18,65 @@ type Item struct {
 	TreeLevel  int
 	IsWorktree bool
 	ParentId   string
+	// ParentPath is the path of the repository of a worktree, which path
+	// patterns of the config are matched against as well
+	ParentPath string
+	// Removed marks an item sent by a streaming provider to take back the
+	// item with the same Id and Path it sent before
+	Removed bool
+	// Metadata holds the values of an item which queries can filter on by
+	// key, e.g. its git branch, languages and tags
+	Metadata map[string][]string
 }
 
 type DataProvider interface {
 	GetItems() ([]Item, error)
 }
+
+// StreamingProvider is a DataProvider which can hand out its items while it is
+// still collecting them. The item channel is closed once all items are sent or
+// ctx is cancelled. Errors are sent on the error channel, which is closed
+// right after the items.
+type StreamingProvider interface {
+	DataProvider
+	StreamItems(ctx context.Context) (<-chan Item, <-chan error)
+}
+
+// Stream returns the items of the provider as a channel, streaming them if the
+// provider supports it. Errors are returned on the error channel, which is
+// closed together with the items.
+func Stream(ctx context.Context, provider DataProvider) (<-chan Item, <-chan error) {
+	if streaming, ok := provider.(StreamingProvider); ok {
+		return streaming.StreamItems(ctx)
+	}
+
+	errs := make(chan error, 1)
+	out := make(chan Item)
+	go func() {
+		defer close(out)
+		defer close(errs)
+
+		items, err := provider.GetItems()
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		for _, item := range items {
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, errs
+}
+
+// noErrors is the error channel of a stream which does not fail
+func noErrors() <-chan error {
+	errs := make(chan error)
+	close(errs)
+	return errs
+}
@@ -1,6 +1,9 @@
 package dataproviders
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"maps"
 	"strings"
 )
@@ -63,6 +66,71 @@ func (dp *DeduplicatorProvider) markAndFilterItems() ([]Item, error) {
 	return append(filteredMultiplexerItems, directoryItems...), nil
 }
 
+// StreamItems sends the directory items as the directory provider finds them.
+// Multiplexer items without a directory are only known once all directories
+// are found, so they are sent last. A failing provider does not stop the
+// items of the other one, its error is sent once the items are done.
+func (dp *DeduplicatorProvider) StreamItems(ctx context.Context) (<-chan Item, <-chan error) {
+	out := make(chan Item)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(out)
+
+		var failures []error
+		defer func() {
+			if err := errors.Join(failures...); err != nil {
+				errs <- err
+			}
+		}()
+
+		multiplexerItems, err := dp.multiplexerProvider.GetItems()
+		if err != nil {
+			failures = append(failures, fmt.Errorf("failed to get multiplexer items: %w", err))
+		}
+		multiplexerIds := flattenItems(multiplexerItems)
+
+		directoryItems, directoryErrs := Stream(ctx, dp.directoryProvider)
+		directoryIds := make(map[string]bool)
+		for item := range directoryItems {
+			if dp.markDuplicates {
+				items := []Item{item}
+				markDuplicatesInItems(&items, multiplexerIds)
+				item = items[0]
+			}
+			ids := flattenItems([]Item{item})
+			if item.Removed {
+				// A session of a directory taken back is sent on its own
+				for id := range ids {
+					delete(directoryIds, id)
+				}
+			} else {
+				maps.Copy(directoryIds, ids)
+			}
+
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := <-directoryErrs; err != nil {
+			failures = append(failures, fmt.Errorf("failed to get directory items: %w", err))
+		}
+
+		for _, item := range filterItems(multiplexerItems, directoryIds) {
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, errs
+}
+
 func flattenItems(items []Item) map[string]bool {
 	itemMap := make(map[string]bool)
 	for _, item := range items {
@@ -88,6 +156,7 @@ func markDuplicatesInItems(items *[]Item, ids map[string]bool) {
 	for i := range *items {
 		if _, found := ids[(*items)[i].Id]; found {
 			(*items)[i].Display = strings.Replace((*items)[i].Display, UNSELECTED_ICON, SELECTED_ICON, 1)
+			(*items)[i] = (*items)[i].WithMetadata(MetadataSession, SessionRunning)
 		}
 		if len((*items)[i].SubItems) > 0 {
 			markDuplicatesInItems(&(*items)[i].SubItems, ids)
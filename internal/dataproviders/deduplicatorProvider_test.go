@@ -1,6 +1,8 @@
 package dataproviders
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -15,6 +17,9 @@ func (m *mockDataProvider) GetItems() ([]Item, error) {
 	return m.items, m.err
 }
 
+// running is the metadata of an item marked as having a session
+var running = map[string][]string{MetadataSession: {SessionRunning}}
+
 func TestDeduplicatorProvider_GetItems_NoDuplicates(t *testing.T) {
 	dirProvider := &mockDataProvider{
 		items: []Item{
@@ -110,9 +115,9 @@ func TestDeduplicatorProvider_GetItems_MarkDuplicates(t *testing.T) {
 
 	expected := []Item{
 		{Id: "mux1", Display: "mux1"},
-		{Id: "dir1", Display: SELECTED_ICON + " dir1"},
+		{Id: "dir1", Display: SELECTED_ICON + " dir1", Metadata: running},
 		{Id: "dir2", Display: UNSELECTED_ICON + " dir2", SubItems: []Item{
-			{Id: "dir3", Display: SELECTED_ICON + " dir3"},
+			{Id: "dir3", Display: SELECTED_ICON + " dir3", Metadata: running},
 		}},
 		{Id: "dir4", Display: UNSELECTED_ICON + " dir4"},
 	}
@@ -149,3 +154,68 @@ func TestDeduplicatorProvider_GetItems_MarkDuplicates_NoDuplicates(t *testing.T)
 		t.Errorf("expected %v, got %v", expected, result)
 	}
 }
+
+func TestDeduplicatorProvider_StreamItems(t *testing.T) {
+	dirProvider := &mockDataProvider{
+		items: []Item{
+			{Id: "dir1", Display: UNSELECTED_ICON + " dir1"},
+			{Id: "dir2", Display: UNSELECTED_ICON + " dir2"},
+		},
+	}
+	muxProvider := &mockDataProvider{
+		items: []Item{
+			{Id: "mux1", Display: "mux1"},
+			{Id: "dir2", Display: "dir2"}, // Duplicate
+		},
+	}
+
+	deduplicator := NewDeduplicatorProvider(dirProvider, muxProvider).WithMarkDuplicates(true)
+
+	result := streamItems(t, deduplicator)
+
+	// Sessions without a directory are only known at the end
+	expected := []Item{
+		{Id: "dir1", Display: UNSELECTED_ICON + " dir1"},
+		{Id: "dir2", Display: SELECTED_ICON + " dir2", Metadata: running},
+		{Id: "mux1", Display: "mux1"},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestStream_ReturnsProviderError(t *testing.T) {
+	provider := &mockDataProvider{err: errors.New("boom")}
+
+	items, errs := Stream(context.Background(), provider)
+	for range items {
+		t.Fatal("expected no items")
+	}
+
+	if err := <-errs; err == nil || err.Error() != "boom" {
+		t.Errorf("expected error boom, got %v", err)
+	}
+}
+
+func TestDeduplicatorProvider_StreamItems_ReportsErrors(t *testing.T) {
+	dirProvider := &mockDataProvider{items: []Item{{Id: "dir1", Display: UNSELECTED_ICON + " dir1"}}}
+	muxProvider := &mockDataProvider{err: errors.New("server exited unexpectedly")}
+
+	stream, errs := NewDeduplicatorProvider(dirProvider, muxProvider).StreamItems(context.Background())
+	var result []Item
+	for item := range stream {
+		result = append(result, item)
+	}
+
+	// The directories are still sent
+	expected := []Item{{Id: "dir1", Display: UNSELECTED_ICON + " dir1"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+
+	err := <-errs
+	if err == nil || err.Error() != "failed to get multiplexer items: server exited unexpectedly" {
+		t.Errorf("expected the multiplexer error, got %v", err)
+	}
+}
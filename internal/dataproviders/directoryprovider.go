@@ -1,70 +1,275 @@
 package dataproviders
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/niedch/mux-session/internal/logger"
 )
 
+// defaultScanWorkers bounds how many directories are scanned at once. The scan
+// is bound by filesystem latency, so it pays off to use more workers than CPUs.
+const defaultScanWorkers = 16
+
 // DirectoryProvider implements DataProvider for directory browsing
 type DirectoryProvider struct {
-	searchPaths []string
+	searchPaths []SearchPath
+	workers     int
 }
 
 // NewDirectoryProvider creates a new directory provider
-func NewDirectoryProvider(searchPaths []string) *DirectoryProvider {
+func NewDirectoryProvider(searchPaths []SearchPath) *DirectoryProvider {
 	return &DirectoryProvider{
 		searchPaths: searchPaths,
+		workers:     defaultScanWorkers,
 	}
 }
 
-// GetItems returns the directories to display
+// WithWorkers sets how many directories are scanned in parallel
+func (dp *DirectoryProvider) WithWorkers(workers int) *DirectoryProvider {
+	dp.workers = max(workers, 1)
+	return dp
+}
+
+// GetItems returns the directories to display. The search paths and their
+// directories are scanned in parallel, but the items keep the order of a
+// serial scan.
 func (dp *DirectoryProvider) GetItems() ([]Item, error) {
 	var dirs []Item
-	for _, searchPath := range dp.searchPaths {
-		entries, err := os.ReadDir(searchPath)
-		if err != nil {
-			logger.Printf("Error when searching Paths: %e", err)
-			continue
-		}
+	items, errs := dp.StreamItems(context.Background())
+	for item := range items {
+		dirs = append(dirs, item)
+	}
 
-		for _, entry := range entries {
-			if !entry.IsDir() {
-				continue
-			}
+	return dirs, <-errs
+}
 
-			if !strings.HasPrefix(entry.Name(), ".") {
-				fullPath := filepath.Join(searchPath, entry.Name())
+// StreamItems sends the directories to display as they are scanned. An item is
+// sent once all items before it in the order of GetItems have been sent.
+// Directories which cannot be read are skipped.
+func (dp *DirectoryProvider) StreamItems(ctx context.Context) (<-chan Item, <-chan error) {
+	return dp.stream(ctx, nil), noErrors()
+}
 
-				display := UNSELECTED_ICON + " " + fullPath
+// stream is StreamItems, calling onVisit with every directory right before it
+// is scanned. onVisit is called from several goroutines at once.
+func (dp *DirectoryProvider) stream(ctx context.Context, onVisit func(dir string)) <-chan Item {
+	roots := make([]*scanNode, len(dp.searchPaths))
+	for i, searchPath := range dp.searchPaths {
+		roots[i] = newScanNode(searchPath, searchPath.Path, 0)
+	}
 
-				containsWorktrees, _ := HasWorktrees(fullPath)
-				if containsWorktrees {
-					display = WORKTREE_ICON + " " + fullPath
-				}
+	queue := newScanQueue()
+	queue.push(roots...)
 
-				item := Item{
-					Id:         filepath.Base(fullPath),
-					Display:    display,
-					Path:       fullPath,
-					IsWorktree: containsWorktrees,
+	for range dp.workers {
+		go func() {
+			for {
+				node, ok := queue.pop()
+				if !ok {
+					return
 				}
-
-				// If this is a worktree, scan for subdirectories
-				if containsWorktrees {
-					subItems := GetSubdirectories(fullPath)
-					if len(subItems) > 0 {
-						logger.Printf("Adding SubItems %d to %s", len(subItems), item.Display)
-						item.SubItems = subItems
+				// After cancellation the remaining directories are only drained
+				if ctx.Err() == nil {
+					if onVisit != nil {
+						onVisit(node.dir)
 					}
+					queue.push(node.visit()...)
 				}
+				close(node.visited)
+				queue.done()
+			}
+		}()
+	}
 
-				dirs = append(dirs, item)
+	out := make(chan Item)
+	go func() {
+		defer close(out)
+		for _, root := range roots {
+			if !root.send(ctx, out) {
+				return
 			}
 		}
+	}()
+
+	return out
+}
+
+// scanNode is a directory of the scan, depth levels below its search path.
+// Its item and children are filled in by the worker visiting it.
+type scanNode struct {
+	searchPath SearchPath
+	dir        string
+	depth      int
+	item       *Item
+	children   []*scanNode
+	// visited is closed once item and children are set
+	visited chan struct{}
+}
+
+func newScanNode(searchPath SearchPath, dir string, depth int) *scanNode {
+	return &scanNode{searchPath: searchPath, dir: dir, depth: depth, visited: make(chan struct{})}
+}
+
+// id identifies the directory by its path below the search path, so that
+// nested directories of the same name, like org-a/api and org-b/api, do not
+// collide. A directory right below the search path is identified by its name.
+func (n *scanNode) id() string {
+	rel, err := filepath.Rel(n.searchPath.Path, n.dir)
+	if err != nil {
+		return filepath.Base(n.dir)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// visit creates the item of the directory and returns the subdirectories
+// which have to be scanned
+func (n *scanNode) visit() []*scanNode {
+	markers := len(n.searchPath.Markers) > 0
+
+	// The search path itself is not an item
+	if n.depth > 0 {
+		isProject := !markers || n.searchPath.hasMarker(n.dir)
+		if isProject {
+			item := newDirectoryItem(n.id(), n.dir)
+			n.item = &item
+		}
+
+		// A marked project is not searched for nested projects
+		if n.depth >= n.searchPath.depth() || (markers && isProject) {
+			return nil
+		}
+	}
+
+	entries, err := os.ReadDir(n.dir)
+	if err != nil {
+		logger.Printf("Error when searching Paths: %e", err)
+		return nil
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		fullPath := filepath.Join(n.dir, entry.Name())
+		if n.searchPath.isExcluded(fullPath) {
+			continue
+		}
+
+		n.children = append(n.children, newScanNode(n.searchPath, fullPath, n.depth+1))
+	}
+
+	return n.children
+}
+
+// send waits for the node and its children to be visited and sends their
+// items in directory order. It returns false if ctx was cancelled.
+func (n *scanNode) send(ctx context.Context, out chan<- Item) bool {
+	select {
+	case <-n.visited:
+	case <-ctx.Done():
+		return false
+	}
+
+	if n.item != nil {
+		select {
+		case out <- *n.item:
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for _, child := range n.children {
+		if !child.send(ctx, out) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// scanQueue hands out the directories to visit. It is unbounded, as visiting
+// a directory queues its subdirectories, and is drained once every queued
+// directory has been visited.
+type scanQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	nodes   []*scanNode
+	pending int
+}
+
+func newScanQueue() *scanQueue {
+	q := &scanQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *scanQueue) push(nodes ...*scanNode) {
+	if len(nodes) == 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nodes = append(q.nodes, nodes...)
+	q.pending += len(nodes)
+	q.cond.Broadcast()
+}
+
+// pop waits for a directory to visit. It returns false once the scan is done.
+func (q *scanQueue) pop() (*scanNode, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.nodes) == 0 && q.pending > 0 {
+		q.cond.Wait()
+	}
+	if q.pending == 0 {
+		return nil, false
+	}
+
+	node := q.nodes[len(q.nodes)-1]
+	q.nodes = q.nodes[:len(q.nodes)-1]
+	return node, true
+}
+
+// done marks a popped directory as visited
+func (q *scanQueue) done() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending--
+	if q.pending == 0 {
+		q.cond.Broadcast()
+	}
+}
+
+func newDirectoryItem(id string, fullPath string) Item {
+	display := UNSELECTED_ICON + " " + fullPath
+
+	containsWorktrees, _ := HasWorktrees(fullPath)
+	if containsWorktrees {
+		display = WORKTREE_ICON + " " + fullPath
+	}
+
+	item := Item{
+		Id:         id,
+		Display:    display,
+		Path:       fullPath,
+		IsWorktree: containsWorktrees,
+	}
+	item = withDirectoryMetadata(item)
+
+	// If this is a worktree, scan for subdirectories
+	if containsWorktrees {
+		subItems := GetSubdirectories(fullPath, id)
+		if len(subItems) > 0 {
+			logger.Printf("Adding SubItems %d to %s", len(subItems), item.Display)
+			item.SubItems = subItems
+		}
 	}
 
-	return dirs, nil
+	return item
 }
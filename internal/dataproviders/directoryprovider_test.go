@@ -0,0 +1,183 @@
+package dataproviders
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeTree creates the directories and, for paths ending in a file name with
+// a dot, the files below root
+func makeTree(t *testing.T, root string, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		full := filepath.Join(root, path)
+		if filepath.Ext(path) != "" && filepath.Base(path) != ".git" {
+			require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+			require.NoError(t, os.WriteFile(full, nil, 0o644))
+			continue
+		}
+		require.NoError(t, os.MkdirAll(full, 0o755))
+	}
+}
+
+func itemPaths(items []Item, root string) []string {
+	var paths []string
+	for _, item := range items {
+		relative, _ := filepath.Rel(root, item.Path)
+		paths = append(paths, relative)
+	}
+	return paths
+}
+
+func TestDirectoryProvider_GetItems(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root,
+		"github.com/acme/api/.git",
+		"github.com/acme/api/internal",
+		"github.com/acme/web/package.json",
+		"github.com/acme/web/node_modules/dep/package.json",
+		"github.com/acme/notes",
+		"github.com/other/tool/go.mod",
+		"github.com/other/archive/old/.git",
+		"gitlab.com/team/svc/.git",
+		".hidden/repo/.git",
+	)
+
+	tests := []struct {
+		name       string
+		searchPath SearchPath
+		expected   []string
+	}{
+		{
+			name:       "plain path lists immediate children",
+			searchPath: SearchPath{Path: root},
+			expected:   []string{"github.com", "gitlab.com"},
+		},
+		{
+			name:       "max depth without markers lists every level",
+			searchPath: SearchPath{Path: root, MaxDepth: 2},
+			expected:   []string{"github.com", "github.com/acme", "github.com/other", "gitlab.com", "gitlab.com/team"},
+		},
+		{
+			name:       "markers stop descending and hide unmarked directories",
+			searchPath: SearchPath{Path: root, MaxDepth: 4, Markers: []string{".git", "go.mod", "package.json"}},
+			expected:   []string{"github.com/acme/api", "github.com/acme/web", "github.com/other/archive/old", "github.com/other/tool", "gitlab.com/team/svc"},
+		},
+		{
+			name:       "max depth limits marker search",
+			searchPath: SearchPath{Path: root, MaxDepth: 3, Markers: []string{".git", "go.mod", "package.json"}},
+			expected:   []string{"github.com/acme/api", "github.com/acme/web", "github.com/other/tool", "gitlab.com/team/svc"},
+		},
+		{
+			name:       "excludes by name and by relative path",
+			searchPath: SearchPath{Path: root, MaxDepth: 4, Markers: []string{".git", "go.mod", "package.json"}, Exclude: []string{"archive", "gitlab.com/*"}},
+			expected:   []string{"github.com/acme/api", "github.com/acme/web", "github.com/other/tool"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, workers := range []int{1, 4, defaultScanWorkers} {
+				items, err := NewDirectoryProvider([]SearchPath{tt.searchPath}).WithWorkers(workers).GetItems()
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, itemPaths(items, root), "with %d workers", workers)
+			}
+		})
+	}
+}
+
+func TestDirectoryProvider_GetItems_KeepsSearchPathOrder(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, "b/two", "b/one", "a/three", "c/four")
+
+	items, err := NewDirectoryProvider([]SearchPath{
+		{Path: filepath.Join(root, "c")},
+		{Path: filepath.Join(root, "missing")},
+		{Path: filepath.Join(root, "b")},
+		{Path: filepath.Join(root, "a")},
+	}).GetItems()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"c/four", "b/one", "b/two", "a/three"}, itemPaths(items, root))
+}
+
+// makeBenchmarkTree creates orgs*repos git repositories, every tenth with a
+// worktree, below a github.com directory
+func makeBenchmarkTree(b *testing.B, orgs int, repos int) string {
+	b.Helper()
+	root := b.TempDir()
+	for org := range orgs {
+		for repo := range repos {
+			repoPath := filepath.Join(root, "github.com", fmt.Sprintf("org-%d", org), fmt.Sprintf("repo-%d", repo))
+			gitDir := filepath.Join(repoPath, ".git")
+			if err := os.MkdirAll(filepath.Join(repoPath, "src"), 0o755); err != nil {
+				b.Fatal(err)
+			}
+			if err := os.MkdirAll(gitDir, 0o755); err != nil {
+				b.Fatal(err)
+			}
+
+			if repo%10 == 0 {
+				worktree := filepath.Join(gitDir, "worktrees", "feature")
+				if err := os.MkdirAll(worktree, 0o755); err != nil {
+					b.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(worktree, "gitdir"), []byte(repoPath+"-feature/.git"), 0o644); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+
+	return root
+}
+
+func BenchmarkDirectoryProvider_GetItems(b *testing.B) {
+	root := makeBenchmarkTree(b, 50, 100)
+	searchPaths := []SearchPath{{Path: filepath.Join(root, "github.com"), MaxDepth: 2, Markers: []string{".git"}}}
+
+	for _, workers := range []int{1, 4, defaultScanWorkers} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			provider := NewDirectoryProvider(searchPaths).WithWorkers(workers)
+			for b.Loop() {
+				items, err := provider.GetItems()
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(items) != 5000 {
+					b.Fatalf("expected 5000 items, got %d", len(items))
+				}
+			}
+		})
+	}
+}
+
+func TestDirectoryProvider_GetItems_Item(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, "org/api/.git")
+
+	items, err := NewDirectoryProvider([]SearchPath{{Path: root, MaxDepth: 2, Markers: []string{".git"}}}).GetItems()
+	require.NoError(t, err)
+
+	path := filepath.Join(root, "org", "api")
+	assert.Equal(t, []Item{{Id: "org/api", Display: UNSELECTED_ICON + " " + path, Path: path}}, items)
+}
+
+func TestDirectoryProvider_GetItems_NestedIdsDoNotCollide(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, "org-a/api/.git", "org-b/api/.git", "web/.git")
+
+	items, err := NewDirectoryProvider([]SearchPath{{Path: root, MaxDepth: 2, Markers: []string{".git"}}}).GetItems()
+	require.NoError(t, err)
+
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.Id)
+	}
+	assert.Equal(t, []string{"org-a/api", "org-b/api", "web"}, ids)
+}
@@ -0,0 +1,119 @@
+package dataproviders
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Keys of the metadata of an item, which queries can filter on with key:value
+const (
+	MetadataBranch  = "branch"
+	MetadataLang    = "lang"
+	MetadataTag     = "tag"
+	MetadataSession = "session"
+)
+
+// SessionRunning is the session metadata of an item with a running session
+const SessionRunning = "running"
+
+// languageMarkers maps files in the root of a project to its language
+var languageMarkers = []struct {
+	file     string
+	language string
+}{
+	{"go.mod", "go"},
+	{"Cargo.toml", "rust"},
+	{"tsconfig.json", "typescript"},
+	{"package.json", "javascript"},
+	{"pyproject.toml", "python"},
+	{"setup.py", "python"},
+	{"requirements.txt", "python"},
+	{"Gemfile", "ruby"},
+	{"pom.xml", "java"},
+	{"build.gradle", "java"},
+	{"build.gradle.kts", "kotlin"},
+	{"mix.exs", "elixir"},
+	{"composer.json", "php"},
+	{"CMakeLists.txt", "c"},
+}
+
+// WithMetadata returns a copy of the item with values added to key. Copies of
+// an item share its metadata, so the item itself is left unchanged.
+func (i Item) WithMetadata(key string, values ...string) Item {
+	if len(values) == 0 {
+		return i
+	}
+
+	metadata := maps.Clone(i.Metadata)
+	if metadata == nil {
+		metadata = make(map[string][]string)
+	}
+	for _, value := range values {
+		if !slices.Contains(metadata[key], value) {
+			metadata[key] = append(slices.Clip(metadata[key]), value)
+		}
+	}
+
+	i.Metadata = metadata
+	return i
+}
+
+// withDirectoryMetadata adds the git branch and languages of the directory of
+// the item
+func withDirectoryMetadata(item Item) Item {
+	if branch := gitBranch(item.Path); branch != "" {
+		item = item.WithMetadata(MetadataBranch, branch)
+	}
+	return item.WithMetadata(MetadataLang, detectLanguages(item.Path)...)
+}
+
+// gitBranch reads the checked out branch of the repository in dir without
+// running git. It is empty outside of a repository and for a detached HEAD.
+func gitBranch(dir string) string {
+	head, err := os.ReadFile(gitHead(dir))
+	if err != nil {
+		return ""
+	}
+
+	branch, ok := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: refs/heads/")
+	if !ok {
+		return ""
+	}
+	return branch
+}
+
+// gitHead is the HEAD file of the repository in dir. A worktree has a .git
+// file pointing to its git directory, which holds its HEAD.
+func gitHead(dir string) string {
+	gitDir := filepath.Join(dir, ".git")
+
+	if content, err := os.ReadFile(gitDir); err == nil {
+		pointer, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir: ")
+		if !ok {
+			return ""
+		}
+		if !filepath.IsAbs(pointer) {
+			pointer = filepath.Join(dir, pointer)
+		}
+		gitDir = pointer
+	}
+
+	return filepath.Join(gitDir, "HEAD")
+}
+
+// detectLanguages returns the languages of the marker files in dir
+func detectLanguages(dir string) []string {
+	var languages []string
+	for _, marker := range languageMarkers {
+		if slices.Contains(languages, marker.language) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, marker.file)); err == nil {
+			languages = append(languages, marker.language)
+		}
+	}
+	return languages
+}
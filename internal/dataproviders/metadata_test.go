@@ -0,0 +1,102 @@
+package dataproviders
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestGitBranch(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, filepath.Join(root, "repo", ".git", "HEAD"), "ref: refs/heads/feature/x\n")
+	writeFile(t, filepath.Join(root, "detached", ".git", "HEAD"), "0123456789abcdef0123456789abcdef01234567\n")
+	writeFile(t, filepath.Join(root, "repo", ".git", "worktrees", "fix", "HEAD"), "ref: refs/heads/fix\n")
+	writeFile(t, filepath.Join(root, "fix", ".git"), "gitdir: "+filepath.Join(root, "repo", ".git", "worktrees", "fix")+"\n")
+	writeFile(t, filepath.Join(root, "relative", ".git"), "gitdir: ../repo/.git\n")
+	makeTree(t, root, "plain")
+
+	tests := []struct {
+		dir      string
+		expected string
+	}{
+		{dir: "repo", expected: "feature/x"},
+		{dir: "detached", expected: ""},
+		{dir: "fix", expected: "fix"},
+		{dir: "relative", expected: "feature/x"},
+		{dir: "plain", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			assert.Equal(t, tt.expected, gitBranch(filepath.Join(root, tt.dir)))
+		})
+	}
+}
+
+func TestDetectLanguages(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, "api/go.mod", "web/package.json", "web/tsconfig.json", "tool/setup.py", "tool/requirements.txt", "docs")
+
+	assert.Equal(t, []string{"go"}, detectLanguages(filepath.Join(root, "api")))
+	assert.Equal(t, []string{"typescript", "javascript"}, detectLanguages(filepath.Join(root, "web")))
+	assert.Equal(t, []string{"python"}, detectLanguages(filepath.Join(root, "tool")))
+	assert.Empty(t, detectLanguages(filepath.Join(root, "docs")))
+}
+
+func TestDirectoryProvider_GetItems_Metadata(t *testing.T) {
+	root := t.TempDir()
+	makeTree(t, root, "api/go.mod")
+	writeFile(t, filepath.Join(root, "api", ".git", "HEAD"), "ref: refs/heads/main\n")
+
+	items, err := NewDirectoryProvider([]SearchPath{{Path: root}}).GetItems()
+	require.NoError(t, err)
+
+	require.Len(t, items, 1)
+	assert.Equal(t, map[string][]string{MetadataBranch: {"main"}, MetadataLang: {"go"}}, items[0].Metadata)
+}
+
+func TestItem_WithMetadata(t *testing.T) {
+	item := Item{Id: "api"}.WithMetadata(MetadataTag, "backend")
+	copied := item
+
+	tagged := copied.WithMetadata(MetadataTag, "backend", "billing")
+
+	assert.Equal(t, []string{"backend"}, item.Metadata[MetadataTag], "copies must not share added values")
+	assert.Equal(t, []string{"backend", "billing"}, tagged.Metadata[MetadataTag])
+	assert.Nil(t, Item{}.WithMetadata(MetadataTag).Metadata)
+}
+
+func TestTagProvider(t *testing.T) {
+	provider := NewTagProvider(&mockDataProvider{items: []Item{
+		{Id: "api", SubItems: []Item{{Id: "fix", ParentId: "api"}}},
+		{Id: "docs"},
+	}}, func(item Item) []string {
+		if item.Id == "docs" {
+			return nil
+		}
+		return []string{"backend"}
+	})
+
+	expected := []Item{
+		{Id: "api", Metadata: map[string][]string{MetadataTag: {"backend"}}, SubItems: []Item{
+			{Id: "fix", ParentId: "api", Metadata: map[string][]string{MetadataTag: {"backend"}}},
+		}},
+		{Id: "docs"},
+	}
+
+	items, err := provider.GetItems()
+	require.NoError(t, err)
+	assert.Equal(t, expected, items)
+
+	assert.Equal(t, expected, streamItems(t, provider))
+}
@@ -0,0 +1,66 @@
+package dataproviders
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SearchPath is a directory whose subdirectories are listed as items. In the
+// config it is either a plain path, which lists the immediate children, or a
+// table with the settings below.
+type SearchPath struct {
+	Path string `koanf:"path"`
+	// MaxDepth is how many levels below Path are searched, 1 if unset
+	MaxDepth int `koanf:"max_depth"`
+	// Markers are files or directories, like ".git", which mark a project.
+	// When set only directories containing a marker are listed, and the
+	// search does not descend into them.
+	Markers []string `koanf:"markers"`
+	// Exclude are globs of directories to skip. A glob containing a slash is
+	// matched against the path relative to Path, otherwise against the name.
+	Exclude []string `koanf:"exclude"`
+}
+
+// UnmarshalText reads the plain string form of a search path
+func (s *SearchPath) UnmarshalText(text []byte) error {
+	*s = SearchPath{Path: string(text)}
+	return nil
+}
+
+func (s SearchPath) depth() int {
+	if s.MaxDepth < 1 {
+		return 1
+	}
+	return s.MaxDepth
+}
+
+func (s SearchPath) isExcluded(dir string) bool {
+	relative, err := filepath.Rel(s.Path, dir)
+	if err != nil {
+		return false
+	}
+
+	for _, pattern := range s.Exclude {
+		subject := filepath.Base(dir)
+		if strings.Contains(pattern, "/") {
+			subject = relative
+		}
+
+		if matched, _ := filepath.Match(pattern, subject); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s SearchPath) hasMarker(dir string) bool {
+	for _, marker := range s.Markers {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
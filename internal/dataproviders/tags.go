@@ -0,0 +1,70 @@
+package dataproviders
+
+import (
+	"context"
+)
+
+// TagProvider adds tags to the items of another provider. The tags are not
+// part of the provider's items, so a cached provider picks up changed tags
+// right away.
+type TagProvider struct {
+	provider DataProvider
+	tags     func(item Item) []string
+}
+
+// NewTagProvider creates a provider which adds the tags returned by tags to
+// every item of provider and its subitems
+func NewTagProvider(provider DataProvider, tags func(item Item) []string) *TagProvider {
+	return &TagProvider{
+		provider: provider,
+		tags:     tags,
+	}
+}
+
+func (tp *TagProvider) GetItems() ([]Item, error) {
+	items, err := tp.provider.GetItems()
+	if err != nil {
+		return nil, err
+	}
+
+	tagged := make([]Item, len(items))
+	for i, item := range items {
+		tagged[i] = tp.tag(item)
+	}
+	return tagged, nil
+}
+
+// StreamItems sends the items of the provider as they arrive, streaming them
+// if the provider supports it. Errors of the provider are passed on.
+func (tp *TagProvider) StreamItems(ctx context.Context) (<-chan Item, <-chan error) {
+	out := make(chan Item)
+	items, errs := Stream(ctx, tp.provider)
+
+	go func() {
+		defer close(out)
+
+		for item := range items {
+			select {
+			case out <- tp.tag(item):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, errs
+}
+
+func (tp *TagProvider) tag(item Item) Item {
+	item = item.WithMetadata(MetadataTag, tp.tags(item)...)
+
+	if len(item.SubItems) > 0 {
+		subItems := make([]Item, len(item.SubItems))
+		for i, subItem := range item.SubItems {
+			subItems[i] = tp.tag(subItem)
+		}
+		item.SubItems = subItems
+	}
+
+	return item
+}
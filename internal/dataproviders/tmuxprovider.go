@@ -1,16 +1,18 @@
 package dataproviders
 
 import (
+	"errors"
+
 	"github.com/niedch/mux-session/internal/tmux"
 )
 
 // TmuxProvider implements DataProvider for directory browsing
 type TmuxProvider struct {
-	tmux *tmux.Tmux
+	tmux tmux.Multiplexer
 }
 
 // NewTmuxProvider creates a new directory provider
-func NewTmuxProvider(tmux *tmux.Tmux) *TmuxProvider {
+func NewTmuxProvider(tmux tmux.Multiplexer) *TmuxProvider {
 	return &TmuxProvider{
 		tmux: tmux,
 	}
@@ -22,7 +24,10 @@ func (dp *TmuxProvider) GetItems() ([]Item, error) {
 	sessions, err := dp.tmux.ListSessions()
 	if err != nil {
 		// Return empty list if no tmux server is running
-		return items, nil
+		if errors.Is(err, tmux.ErrNoServer) {
+			return items, nil
+		}
+		return nil, err
 	}
 
 	for _, session := range sessions {
@@ -30,7 +35,7 @@ func (dp *TmuxProvider) GetItems() ([]Item, error) {
 			Id:      session,
 			Display: TMUX_ICON + " " + session,
 			Path:    session,
-		})
+		}.WithMetadata(MetadataSession, SessionRunning))
 	}
 
 	return items, nil
@@ -19,7 +19,9 @@ func HasWorktrees(parentPath string) (bool, error) {
 	return false, nil
 }
 
-func GetSubdirectories(parentPath string) []Item {
+// GetSubdirectories returns the worktrees of the repository at parentPath as
+// sub items of the item parentId
+func GetSubdirectories(parentPath string, parentId string) []Item {
 	var subItems []Item
 	worktreeDefinitions := filepath.Join(parentPath, ".git", "worktrees")
 
@@ -44,14 +46,15 @@ func GetSubdirectories(parentPath string) []Item {
 
 		display := UNSELECTED_ICON + " " + entry.Name()
 
-		subItems = append(subItems, Item{
+		subItems = append(subItems, withDirectoryMetadata(Item{
 			Id:         entry.Name(),
 			Display:    display,
 			Path:       itemDir,
 			IsWorktree: false,
 			TreeLevel:  1,
-			ParentId:   filepath.Base(parentPath),
-		})
+			ParentId:   parentId,
+			ParentPath: parentPath,
+		}))
 	}
 
 	return subItems
@@ -1,13 +1,20 @@
 package fzf
 
 import (
+	"context"
+	"fmt"
 	"os"
 
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/niedch/mux-session/internal/conf"
 	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/niedch/mux-session/internal/logger"
+	"github.com/niedch/mux-session/internal/orchestrator"
+	"github.com/niedch/mux-session/internal/pins"
 	"github.com/niedch/mux-session/internal/previewproviders"
+	"github.com/niedch/mux-session/internal/tmux"
 	"golang.org/x/term"
 )
 
@@ -21,8 +28,33 @@ var (
 		Border(lipgloss.NormalBorder(), false, true, false, false)
 )
 
+// maxItemBatch bounds how many streamed items are added at once, so the list
+// is filtered once per batch instead of once per item
+const maxItemBatch = 256
+
+const (
+	// ResultOpen opens the sessions of the items and switches to the last one
+	ResultOpen = "open"
+	// ResultPrint prints the Ids of the items
+	ResultPrint = "print"
+)
+
+// Result is what the user picked: the items and what to do with them
+type Result struct {
+	Action string
+	Items  []dataproviders.Item
+}
+
 type previewUpdateMsg struct{}
 
+type itemsMsg struct {
+	items []dataproviders.Item
+}
+
+type itemsDoneMsg struct {
+	err error
+}
+
 func waitForPreviewUpdate(ch <-chan struct{}) tea.Cmd {
 	return func() tea.Msg {
 		<-ch
@@ -30,13 +62,49 @@ func waitForPreviewUpdate(ch <-chan struct{}) tea.Cmd {
 	}
 }
 
-func Run(dataProvider dataproviders.DataProvider, config *conf.Config) (*dataproviders.Item, error) {
-	items, err := dataProvider.GetItems()
-	if err != nil {
-		return nil, err
+// waitForItems reads the next batch of streamed items: everything already
+// available, up to maxItemBatch
+func waitForItems(items <-chan dataproviders.Item, errs <-chan error) tea.Cmd {
+	return func() tea.Msg {
+		item, ok := <-items
+		if !ok {
+			return itemsDoneMsg{err: <-errs}
+		}
+
+		batch := []dataproviders.Item{item}
+		for len(batch) < maxItemBatch {
+			select {
+			case item, ok := <-items:
+				if !ok {
+					return itemsMsg{items: batch}
+				}
+				batch = append(batch, item)
+			default:
+				return itemsMsg{items: batch}
+			}
+		}
+
+		return itemsMsg{items: batch}
 	}
+}
 
-	w, h, err := term.GetSize(int(os.Stdout.Fd()))
+// Run shows the picker with the items of dataProvider. The sessions of
+// multiplexer can be killed, renamed and detached from the picker, which then
+// reloads the items from dataProvider, except that currentSession, the session
+// the picker runs in, is not killed. The result is nil if the user quit
+// without picking an item.
+func Run(dataProvider dataproviders.DataProvider, multiplexer tmux.Multiplexer, currentSession string, config *conf.Config) (*Result, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The picker is drawn on stderr when stdout is piped, so that printed Ids
+	// can be read by another program
+	output := os.Stdout
+	if !term.IsTerminal(int(output.Fd())) {
+		output = os.Stderr
+	}
+
+	w, h, err := term.GetSize(int(output.Fd()))
 	if err != nil {
 		return nil, err
 	}
@@ -51,14 +119,42 @@ func Run(dataProvider dataproviders.DataProvider, config *conf.Config) (*datapro
 	updateChan := make(chan struct{}, 1)
 	previewProvider.SetUpdateChan(updateChan)
 
-	p := tea.NewProgram(initialModel(items, previewProvider, updateChan, leftVpWidth, rightVpWidth, h), tea.WithAltScreen())
+	sorter, err := NewSorter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	field := NewField(config)
+
+	pinStore, err := pins.DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := pinStore.Set()
+	if err != nil {
+		return nil, err
+	}
+
+	items, errs := dataproviders.Stream(ctx, dataProvider)
+
+	picker := initialModel(dataProvider, multiplexer, items, errs, sorter, field, config.CollapseWorktrees, newKeymap(config.KeyBindings()), pinStore, pinned, previewProvider, updateChan, leftVpWidth, rightVpWidth, h)
+	picker.currentSession = currentSession
+	picker.sessionName = func(item dataproviders.Item) string {
+		projectConfig, err := config.GetProjectConfig(&item)
+		if err != nil {
+			return tmux.SessionName(item.Id)
+		}
+		return orchestrator.SessionName(&item, projectConfig)
+	}
+
+	p := tea.NewProgram(picker, tea.WithAltScreen(), tea.WithOutput(output))
 	m, err := p.Run()
 	if err != nil {
 		return nil, err
 	}
 
 	if model, ok := m.(model); ok {
-		return model.selected, nil
+		return model.result, nil
 	}
 
 	return nil, nil
@@ -67,32 +163,49 @@ func Run(dataProvider dataproviders.DataProvider, config *conf.Config) (*datapro
 type model struct {
 	searchPort    *searchPort
 	previewPort   *previewPort
-	selected      *dataproviders.Item
+	result        *Result
 	lastSelection *dataproviders.Item
 	width         int
 	height        int
 	updateChan    <-chan struct{}
+	items         <-chan dataproviders.Item
+	errs          <-chan error
+	pins          *pins.Store
+	dataProvider  dataproviders.DataProvider
+	multiplexer   tmux.Multiplexer
+	// currentSession is the session the picker runs in
+	currentSession string
+	// sessionName names the session of an item as the orchestrator does
+	sessionName func(item dataproviders.Item) string
+	// prompt asks for the input of a session action while it is set
+	prompt *sessionPrompt
+	// previewHidden gives the whole width to the search port
+	previewHidden bool
 }
 
-func initialModel(items []dataproviders.Item, provider previewproviders.PreviewProvider, updateChan <-chan struct{}, leftVpWidth, rightVpWidth, h int) model {
-	sp := newSearchPort(items, leftVpWidth, h)
+func initialModel(dataProvider dataproviders.DataProvider, multiplexer tmux.Multiplexer, items <-chan dataproviders.Item, errs <-chan error, sorter Sorter, field Field, collapsed bool, km keymap, pinStore *pins.Store, pinned map[string]bool, provider previewproviders.PreviewProvider, updateChan <-chan struct{}, leftVpWidth, rightVpWidth, h int) model {
+	sp := newSearchPort(nil, sorter, field, leftVpWidth, h)
+	sp.SetCollapsed(collapsed)
+	sp.SetKeymap(km)
+	sp.SetPinned(pinned)
 	return model{
-		searchPort:    sp,
-		previewPort:   newPreviewPort(provider, rightVpWidth, h),
-		updateChan:    updateChan,
-		lastSelection: sp.GetSelected(),
+		pins:         pinStore,
+		searchPort:   sp,
+		previewPort:  newPreviewPort(provider, rightVpWidth, h),
+		updateChan:   updateChan,
+		items:        items,
+		errs:         errs,
+		dataProvider: dataProvider,
+		multiplexer:  multiplexer,
 	}
 }
 
 func (m model) Init() tea.Cmd {
 	var cmds []tea.Cmd
 
-	// Load initial README if items exist
-	if m.lastSelection != nil {
-		m.previewPort.LoadItem(m.lastSelection)
-	}
-
 	cmds = append(cmds, m.searchPort.textInput.Focus())
+	cmds = append(cmds, m.searchPort.StartLoading())
+	cmds = append(cmds, waitForItems(m.items, m.errs))
 	cmds = append(cmds, waitForPreviewUpdate(m.updateChan))
 
 	return tea.Batch(cmds...)
@@ -103,20 +216,43 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "esc":
+		if m.prompt != nil {
+			return m.updatePrompt(msg)
+		}
+		km := m.searchPort.keymap
+		switch {
+		case key.Matches(msg, km.quit):
 			return m, tea.Quit
-		case "enter":
-			m.selected = m.searchPort.GetSelected()
+		case key.Matches(msg, km.selectItem):
+			m.result = m.pick(ResultOpen)
 			return m, tea.Quit
+		case key.Matches(msg, km.print):
+			m.result = m.pick(ResultPrint)
+			return m, tea.Quit
+		case key.Matches(msg, km.pin):
+			m.togglePin()
+			return m, nil
+		case key.Matches(msg, km.kill):
+			return m.startSessionAction(actionKill)
+		case key.Matches(msg, km.rename):
+			return m.startSessionAction(actionRename)
+		case key.Matches(msg, km.detach):
+			return m.startSessionAction(actionDetach)
+		case key.Matches(msg, km.togglePreview):
+			m.previewHidden = !m.previewHidden
+			m.resize()
+			return m, nil
+		case key.Matches(msg, km.previewUp):
+			m.previewPort.PageUp()
+			return m, nil
+		case key.Matches(msg, km.previewDown):
+			m.previewPort.PageDown()
+			return m, nil
 		}
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		leftVpWidth, rightVpWidth := calculateLayout(m.width)
-
-		m.searchPort.SetSize(leftVpWidth, msg.Height)
-		m.previewPort.SetSize(rightVpWidth, msg.Height)
+		m.resize()
 	case previewUpdateMsg:
 		// When an update is received, reload the currently selected item
 		// and listen for the next update
@@ -124,6 +260,29 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.previewPort.ReloadItem()
 		}
 		return m, waitForPreviewUpdate(m.updateChan)
+	case itemsMsg:
+		m.searchPort.AddItems(msg.items)
+		cmds = append(cmds, waitForItems(m.items, m.errs))
+	case sessionActionMsg:
+		if msg.err != nil {
+			// Reloaded anyway, a bulk action may have partially succeeded
+			m.searchPort.SetStatus(msg.err.Error())
+		} else {
+			m.searchPort.SetStatus(msg.message)
+		}
+		return m, refreshItems(m.dataProvider)
+	case refreshMsg:
+		if msg.err != nil {
+			m.searchPort.SetStatus(fmt.Sprintf("Failed to reload items: %v", msg.err))
+			return m, nil
+		}
+		m.searchPort.ReplaceItems(msg.items)
+	case itemsDoneMsg:
+		m.searchPort.StopLoading()
+		// The items which were loaded can still be picked
+		if msg.err != nil {
+			m.searchPort.SetStatus(fmt.Sprintf("Failed to load items: %v", msg.err))
+		}
 	}
 
 	// Update searchPort first (this moves the cursor)
@@ -137,13 +296,77 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.previewPort.LoadItem(currentSelection)
 	}
 
-	cmd = m.previewPort.Update(msg)
-	cmds = append(cmds, cmd)
+	// Keys of the picker's actions do not scroll the preview
+	if keyMsg, ok := msg.(tea.KeyMsg); !ok || !m.searchPort.keymap.bound(keyMsg) {
+		cmd = m.previewPort.Update(msg)
+		cmds = append(cmds, cmd)
+	}
 
 	return m, tea.Batch(cmds...)
 }
 
+// pick returns the marked items, or the selected item if none are marked
+func (m model) pick(action string) *Result {
+	if marked := m.searchPort.MarkedItems(); len(marked) > 0 {
+		return &Result{Action: action, Items: marked}
+	}
+	if selected := m.searchPort.GetSelected(); selected != nil {
+		return &Result{Action: action, Items: []dataproviders.Item{*selected}}
+	}
+	return nil
+}
+
+// togglePin pins or unpins the selected item. Only top level items are
+// grouped, so a worktree pins or unpins its repository.
+func (m model) togglePin() {
+	selected := m.searchPort.GetSelected()
+	if selected == nil {
+		return
+	}
+
+	id := selected.Id
+	if selected.ParentId != "" {
+		id = selected.ParentId
+	}
+
+	nowPinned, err := m.pins.Toggle(id)
+	if err != nil {
+		logger.Printf("Failed to pin %s: %v\n", id, err)
+		return
+	}
+	if id != selected.Id {
+		verb := "Unpinned"
+		if nowPinned {
+			verb = "Pinned"
+		}
+		m.searchPort.SetStatus(fmt.Sprintf("%s %s, the repository of worktree %s", verb, id, selected.Id))
+	}
+
+	pinned, err := m.pins.Set()
+	if err != nil {
+		logger.Printf("Failed to read pins: %v\n", err)
+		return
+	}
+	m.searchPort.SetPinned(pinned)
+}
+
+// resize lays out the ports for the window size
+func (m model) resize() {
+	if m.previewHidden {
+		m.searchPort.SetSize(m.width, m.height)
+		return
+	}
+
+	leftVpWidth, rightVpWidth := calculateLayout(m.width)
+	m.searchPort.SetSize(leftVpWidth, m.height)
+	m.previewPort.SetSize(rightVpWidth, m.height)
+}
+
 func (m model) View() string {
+	if m.previewHidden {
+		return m.searchPort.View()
+	}
+
 	searchView := rightBorderStyle.Width(m.searchPort.width + 1).Render(m.searchPort.View())
 	previewView := m.previewPort.View()
 
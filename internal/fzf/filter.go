@@ -1,66 +1,367 @@
 package fzf
 
 import (
+	"math"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/niedch/mux-session/internal/dataproviders"
 )
 
+// The scores follow the v2 algorithm of fzf: every matched character scores
+// scoreMatch plus the bonus of its position, gaps between matches cost
+// scoreGapStart for their first character and scoreGapExtension for every
+// further one.
 const (
-	prefixMatchBonus = 100
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	// bonusBoundary is given to a match at the start of a word
+	bonusBoundary = scoreMatch / 2
+	// bonusBoundaryWhite and bonusBoundaryDelimiter rank word starts after a
+	// space or a path separator above other word starts
+	bonusBoundaryWhite     = bonusBoundary + 2
+	bonusBoundaryDelimiter = bonusBoundary + 1
+	// bonusNonWord is given to a matched separator like "-" or "."
+	bonusNonWord = scoreMatch / 2
+	// bonusCamel123 is given to the start of a camelCase hump or a number
+	bonusCamel123 = bonusBoundary + scoreGapExtension
+	// bonusConsecutive is the least bonus of a match right after a match, so
+	// that a run of matches beats matches spread out with gaps
+	bonusConsecutive = -(scoreGapStart + scoreGapExtension)
+	// bonusFirstCharMultiplier weighs the bonus of the first query character
+	bonusFirstCharMultiplier = 2
+	// bonusBasename is added to every match in the last path segment, which
+	// names the project
+	bonusBasename = scoreMatch / 4
 )
 
-// getMatches finds the byte offsets of characters in lowercased text
-// that match the query runes in sequence (subsequence/fuzzy match).
-func getMatches(textLower string, queryRunes []rune) []int {
-	if len(queryRunes) == 0 || len(queryRunes) > len(textLower) {
-		return nil
+// unmatched marks a cell of the score matrix without a valid alignment
+const unmatched = math.MinInt32
+
+type charClass int
+
+const (
+	charWhite charClass = iota
+	charNonWord
+	charDelimiter
+	charLower
+	charUpper
+	charLetter
+	charNumber
+)
+
+func classOf(r rune) charClass {
+	switch {
+	case unicode.IsLower(r):
+		return charLower
+	case unicode.IsUpper(r):
+		return charUpper
+	case unicode.IsLetter(r):
+		return charLetter
+	case unicode.IsNumber(r):
+		return charNumber
+	case unicode.IsSpace(r):
+		return charWhite
+	case strings.ContainsRune("/,:;|", r):
+		return charDelimiter
+	}
+	return charNonWord
+}
+
+// bonusFor is the bonus of a match on a character of class after a character
+// of class prev
+func bonusFor(prev, class charClass) int {
+	if class >= charLower {
+		switch prev {
+		case charWhite:
+			return bonusBoundaryWhite
+		case charDelimiter:
+			return bonusBoundaryDelimiter
+		case charNonWord:
+			return bonusBoundary
+		}
+	}
+
+	if prev == charLower && class == charUpper || prev != charNumber && class == charNumber {
+		return bonusCamel123
+	}
+
+	switch class {
+	case charNonWord, charDelimiter:
+		return bonusNonWord
+	case charWhite:
+		return bonusBoundaryWhite
+	}
+	return 0
+}
+
+// scratchPool reuses the buffers of the matchers, which would otherwise
+// allocate for every item on every key press
+var scratchPool = sync.Pool{New: func() any { return new([]int32) }}
+
+// scratch is a buffer of the pool cut into slices with carve
+type scratch struct {
+	buf  *[]int32
+	free []int32
+}
+
+func getScratch(size int) scratch {
+	buf := scratchPool.Get().(*[]int32)
+	if cap(*buf) < size {
+		*buf = make([]int32, size)
+	}
+	return scratch{buf: buf, free: (*buf)[:size]}
+}
+
+func (s *scratch) carve(length int) []int32 {
+	part := s.free[:length]
+	s.free = s.free[length:]
+	return part
+}
+
+func (s *scratch) release() {
+	scratchPool.Put(s.buf)
+}
+
+// analyze fills lower, bonus and offsets with the lowercased runes of text
+// from rune index first to last, their bonus and their byte offset. It
+// returns where the last path segment starts, relative to first.
+func analyze(text string, first, last int, lower, bonus, offsets []int32) int {
+	prevClass, base := charWhite, -1
+	j := 0
+	for offset, r := range text {
+		class := classOf(r)
+		if r == '/' {
+			base = j
+		}
+		if j >= first && j <= last {
+			lower[j-first] = unicode.ToLower(r)
+			bonus[j-first] = int32(bonusFor(prevClass, class))
+			offsets[j-first] = int32(offset)
+		}
+		prevClass = class
+		j++
 	}
-	matches := make([]int, 0, len(queryRunes))
-	qi := 0
-	for bi, r := range textLower {
-		if queryRunes[qi] == r {
-			matches = append(matches, bi)
+	return base + 1 - first
+}
+
+// fuzzyMatch scores how well text matches the lowercased query runes, which
+// have to appear in text in order. With withPositions it returns the byte
+// offsets of the matched characters of the best alignment, not just the first
+// one, which filtering does not need. It returns false if text does not
+// contain the query.
+func fuzzyMatch(text string, query []rune, withPositions bool) (int, []int, bool) {
+	m := len(query)
+	if m == 0 {
+		return 0, nil, false
+	}
+
+	// Reject texts without the query before allocating anything, and narrow
+	// the text down to where an alignment can start and end
+	first, last, qi := -1, -1, 0
+	n := 0
+	for _, r := range text {
+		r = unicode.ToLower(r)
+		if qi < m && r == query[qi] {
+			if qi == 0 {
+				first = n
+			}
 			qi++
-			if qi == len(queryRunes) {
-				return matches
+		}
+		if qi == m && r == query[m-1] {
+			last = n
+		}
+		n++
+	}
+	if qi < m {
+		return 0, nil, false
+	}
+
+	width := last - first + 1
+	sc := getScratch(width * (m + 11))
+	defer sc.release()
+
+	lower, bonus, offsets := sc.carve(width), sc.carve(width), sc.carve(width)
+	// from holds the position of the previous query character for every
+	// match, the rows of scores only hold the current and the previous one
+	from := sc.carve(width * m)
+	prevM, curM := sc.carve(width), sc.carve(width)
+	prevG, curG := sc.carve(width), sc.carve(width)
+	prevGapFrom, curGapFrom := sc.carve(width), sc.carve(width)
+	prevRun, curRun := sc.carve(width), sc.carve(width)
+
+	base := analyze(text, first, last, lower, bonus, offsets)
+
+	for i := 0; i < m; i++ {
+		for j := range width {
+			curM[j], curG[j] = unmatched, unmatched
+			if j < i {
+				continue
+			}
+
+			// Gap: query[i] was matched left of j
+			if j > 0 {
+				if s := curM[j-1]; s != unmatched && s+scoreGapStart > curG[j] {
+					curG[j], curGapFrom[j] = s+scoreGapStart, int32(j-1)
+				}
+				if s := curG[j-1]; s != unmatched && s+scoreGapExtension > curG[j] {
+					curG[j], curGapFrom[j] = s+scoreGapExtension, curGapFrom[j-1]
+				}
+			}
+
+			if lower[j] != query[i] {
+				continue
+			}
+
+			score := int32(scoreMatch)
+			if j >= base {
+				score += bonusBasename
+			}
+
+			if i == 0 {
+				curM[j], curRun[j], from[j] = score+bonus[j]*bonusFirstCharMultiplier, 1, -1
+				continue
+			}
+			if j == 0 {
+				continue
+			}
+
+			// After a gap
+			if s := prevG[j-1]; s != unmatched {
+				curM[j], curRun[j], from[i*width+j] = s+score+bonus[j], 1, prevGapFrom[j-1]
+			}
+
+			// Right after the previous query character. The run gets at
+			// least the bonus of its first character, unless a word starts
+			// here which is worth more.
+			if s := prevM[j-1]; s != unmatched {
+				run := prevRun[j-1] + 1
+				b, runBonus := bonus[j], bonus[j-int(run)+1]
+				if b >= bonusBoundary && b > runBonus {
+					run = 1
+				} else {
+					b = max(b, bonusConsecutive, runBonus)
+				}
+				if s+score+b > curM[j] {
+					curM[j], curRun[j], from[i*width+j] = s+score+b, run, int32(j-1)
+				}
 			}
 		}
+
+		prevM, curM = curM, prevM
+		prevG, curG = curG, prevG
+		prevGapFrom, curGapFrom = curGapFrom, prevGapFrom
+		prevRun, curRun = curRun, prevRun
+	}
+
+	best, end := int32(unmatched), -1
+	for j := range width {
+		if prevM[j] > best {
+			best, end = prevM[j], j
+		}
 	}
-	return nil
+	if !withPositions {
+		return int(best), nil, true
+	}
+
+	positions := make([]int, m)
+	for i := m - 1; i >= 0; i-- {
+		positions[i] = int(offsets[end])
+		end = int(from[i*width+end])
+	}
+
+	return int(best), positions, true
 }
 
-// getMatchQuality scores how well lowercased text matches the lowercased query
-// as a contiguous substring. Higher is better.
-func getMatchQuality(textLower string, queryLower string) int {
-	matchStart := strings.Index(textLower, queryLower)
-	if matchStart == -1 {
-		return 0
+// exactMatch finds the lowercased pattern as a substring of text, at its
+// start if prefix is set and at its end if suffix is set. Of several
+// occurrences the one scoring best is returned, with its byte offsets if
+// withPositions is set.
+func exactMatch(text string, pattern []rune, prefix, suffix, withPositions bool) (int, []int, bool) {
+	m, n := len(pattern), utf8.RuneCountInString(text)
+	if m == 0 || m > n {
+		return 0, nil, false
+	}
+
+	sc := getScratch(n * 3)
+	defer sc.release()
+
+	lower, bonus, offsets := sc.carve(n), sc.carve(n), sc.carve(n)
+	base := analyze(text, 0, n-1, lower, bonus, offsets)
+
+	best, bestStart := int32(unmatched), -1
+	for start := 0; start+m <= n; start++ {
+		if prefix && start > 0 || suffix && start+m < n {
+			continue
+		}
+		if !slices.Equal(lower[start:start+m], pattern) {
+			continue
+		}
+
+		// Scored like a run of fuzzy matches
+		var score int32
+		runBonus := bonus[start]
+		for k := range m {
+			j := start + k
+			b := bonus[j]
+			if k == 0 {
+				b *= bonusFirstCharMultiplier
+			} else {
+				if b >= bonusBoundary && b > runBonus {
+					runBonus = b
+				}
+				b = max(b, bonusConsecutive, runBonus)
+			}
+			score += scoreMatch + b
+			if j >= base {
+				score += bonusBasename
+			}
+		}
+
+		if score > best {
+			best, bestStart = score, start
+		}
+	}
+
+	if bestStart < 0 {
+		return 0, nil, false
 	}
-	suffixLength := len(textLower) - len(queryLower)
-	if matchStart == 0 {
-		return prefixMatchBonus + suffixLength
+	if !withPositions {
+		return int(best), nil, true
+	}
+
+	positions := make([]int, m)
+	for k := range m {
+		positions[k] = int(offsets[bestStart+k])
 	}
-	return suffixLength + 1
+	return int(best), positions, true
 }
 
-// filterNode recursively filters children of an item whose display did not match.
-// It returns the (potentially modified) item and a boolean indicating if a match was found.
-func filterNode(item dataproviders.Item, queryLower string, queryRunes []rune) (dataproviders.Item, bool) {
+// filterNode recursively filters children of an item whose field did not match.
+// It returns the (potentially modified) item, the best score of its children
+// and a boolean indicating if a match was found.
+func filterNode(item dataproviders.Item, query searchQuery, field Field) (dataproviders.Item, int, bool) {
 	if len(item.SubItems) == 0 {
-		return dataproviders.Item{}, false
+		return dataproviders.Item{}, 0, false
 	}
 
 	var matchingChildren []dataproviders.Item
+	best := math.MinInt
 	for _, child := range item.SubItems {
-		textLower := strings.ToLower(child.Display)
-		if getMatches(textLower, queryRunes) != nil {
+		if score, ok := query.score(child, field(child)); ok {
 			matchingChildren = append(matchingChildren, child)
+			best = max(best, score)
 		} else {
-			filteredChild, childMatches := filterNode(child, queryLower, queryRunes)
+			filteredChild, score, childMatches := filterNode(child, query, field)
 			if childMatches {
 				matchingChildren = append(matchingChildren, filteredChild)
+				best = max(best, score)
 			}
 		}
 	}
@@ -68,24 +369,26 @@ func filterNode(item dataproviders.Item, queryLower string, queryRunes []rune) (
 	if len(matchingChildren) > 0 {
 		newItem := item
 		newItem.SubItems = matchingChildren
-		return newItem, true
+		return newItem, best, true
 	}
 
-	return dataproviders.Item{}, false
+	return dataproviders.Item{}, 0, false
 }
 
-// FilterTree filters a slice of items based on a query.
+// FilterTree filters a slice of items based on a query in the extended search
+// syntax of fzf, matched against the field and the metadata of the items.
 // It returns a new tree containing only items that match or have children that match.
-// Items are sorted by match quality (longer items with the query as prefix come first).
-func FilterTree(items []dataproviders.Item, query string) []dataproviders.Item {
-	queryLower := strings.ToLower(query)
-	if len(queryLower) == 0 {
+// Items are sorted by their match score, the best match last, and an item
+// kept for its children scores as its best child. Equally good matches keep
+// their order.
+func FilterTree(items []dataproviders.Item, query string, field Field) []dataproviders.Item {
+	parsed := parseQuery(query)
+	if len(parsed) == 0 {
 		return items
 	}
 	if len(items) == 0 {
 		return nil
 	}
-	queryRunes := []rune(queryLower)
 
 	type itemWithQuality struct {
 		item    dataproviders.Item
@@ -94,14 +397,12 @@ func FilterTree(items []dataproviders.Item, query string) []dataproviders.Item {
 
 	itemsWithQuality := make([]itemWithQuality, 0, len(items))
 	for _, item := range items {
-		textLower := strings.ToLower(item.Display)
-		if getMatches(textLower, queryRunes) != nil {
-			quality := getMatchQuality(textLower, queryLower)
-			itemsWithQuality = append(itemsWithQuality, itemWithQuality{item: item, quality: quality})
+		if score, ok := parsed.score(item, field(item)); ok {
+			itemsWithQuality = append(itemsWithQuality, itemWithQuality{item: item, quality: score})
 		} else {
-			filteredItem, hasMatch := filterNode(item, queryLower, queryRunes)
+			filteredItem, score, hasMatch := filterNode(item, parsed, field)
 			if hasMatch {
-				itemsWithQuality = append(itemsWithQuality, itemWithQuality{item: filteredItem, quality: 0})
+				itemsWithQuality = append(itemsWithQuality, itemWithQuality{item: filteredItem, quality: score})
 			}
 		}
 	}
@@ -110,7 +411,8 @@ func FilterTree(items []dataproviders.Item, query string) []dataproviders.Item {
 		return nil
 	}
 
-	sort.Slice(itemsWithQuality, func(i, j int) bool {
+	// Equally good matches keep the order of items
+	sort.SliceStable(itemsWithQuality, func(i, j int) bool {
 		return itemsWithQuality[i].quality < itemsWithQuality[j].quality
 	})
 
@@ -123,18 +425,17 @@ func FilterTree(items []dataproviders.Item, query string) []dataproviders.Item {
 }
 
 // createListItems creates the list of listItem models for rendering.
-func createListItems(items []dataproviders.Item, query string) []listItem {
-	queryLower := strings.ToLower(query)
-	queryRunes := []rune(queryLower)
+func createListItems(items []dataproviders.Item, query string, field Field) []listItem {
+	parsed := parseQuery(query)
 	result := make([]listItem, 0, len(items))
 
 	for i, item := range items {
-		textLower := strings.ToLower(item.Display)
-		matches := getMatches(textLower, queryRunes)
+		text := field(item)
+		_, matches, _ := parsed.match(item, text)
 		result = append(result, listItem{
 			text:    item.Display,
 			index:   i,
-			matches: matches,
+			matches: displayPositions(item.Display, text, matches),
 		})
 	}
 	return result
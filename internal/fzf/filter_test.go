@@ -1,6 +1,7 @@
 package fzf
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -16,10 +17,10 @@ func TestFilterTree_Normal(t *testing.T) {
 		{Display: "cherry"},
 	}
 
-	assert.Equal(t, "apple\n", renderItems(FilterTree(items, "ap")))
-	assert.Equal(t, "banana\n", renderItems(FilterTree(items, "nan")))
-	assert.Equal(t, "", renderItems(FilterTree(items, "z")))
-	assert.Equal(t, "apple\nbanana\ncherry\n", renderItems(FilterTree(items, "")))
+	assert.Equal(t, "apple\n", renderItems(FilterTree(items, "ap", DisplayField)))
+	assert.Equal(t, "banana\n", renderItems(FilterTree(items, "nan", DisplayField)))
+	assert.Equal(t, "", renderItems(FilterTree(items, "z", DisplayField)))
+	assert.Equal(t, "apple\nbanana\ncherry\n", renderItems(FilterTree(items, "", DisplayField)))
 }
 
 func TestFilterTree_PrioritizeCloserMatches(t *testing.T) {
@@ -29,7 +30,7 @@ func TestFilterTree_PrioritizeCloserMatches(t *testing.T) {
 		{Display: "/home/nic/mux-prompt"},
 	}
 
-	assert.Equal(t, "/home/nic/mux-prompt\n/home/nic/nixos-dotfiles\n", renderItems(FilterTree(items, "nix")))
+	assert.Equal(t, "/home/nic/mux-prompt\n/home/nic/nixos-dotfiles\n", renderItems(FilterTree(items, "nix", DisplayField)))
 }
 
 func TestFilterTree_WithSubItems(t *testing.T) {
@@ -49,9 +50,9 @@ func TestFilterTree_WithSubItems(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, "fruit\n  apple\n  banana\n", renderItems(FilterTree(items, "fruit")))
-	assert.Equal(t, "fruit\n  apple\n", renderItems(FilterTree(items, "apple")))
-	assert.Equal(t, "vegetable\n  carrot\n", renderItems(FilterTree(items, "carrot")))
+	assert.Equal(t, "fruit\n  apple\n  banana\n", renderItems(FilterTree(items, "fruit", DisplayField)))
+	assert.Equal(t, "fruit\n  apple\n", renderItems(FilterTree(items, "apple", DisplayField)))
+	assert.Equal(t, "vegetable\n  carrot\n", renderItems(FilterTree(items, "carrot", DisplayField)))
 }
 
 
@@ -71,3 +72,106 @@ func renderItemsRec(b *strings.Builder, items []dataproviders.Item, indent strin
 		}
 	}
 }
+
+func TestFuzzyMatch_Positions(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		query     string
+		positions []int
+	}{
+		{name: "word starts over the first occurrence", text: "/home/x/mux-session", query: "ms", positions: []int{8, 12}},
+		{name: "consecutive run", text: "a-list/alpha", query: "alp", positions: []int{7, 8, 9}},
+		{name: "camel case", text: "myFooBar", query: "fb", positions: []int{2, 5}},
+		{name: "byte offsets after multi-byte runes", text: "󰄱 ab", query: "b", positions: []int{6}},
+		{name: "case insensitive", text: "README", query: "me", positions: []int{4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, positions, ok := fuzzyMatch(tt.text, []rune(tt.query), true)
+
+			assert.True(t, ok)
+			assert.Equal(t, tt.positions, positions)
+		})
+	}
+}
+
+func TestFuzzyMatch_NoMatch(t *testing.T) {
+	_, positions, ok := fuzzyMatch("mux-session", []rune("sm"), true)
+
+	assert.False(t, ok)
+	assert.Nil(t, positions)
+}
+
+func TestFuzzyMatch_WithoutPositions(t *testing.T) {
+	score, _, _ := fuzzyMatch("/home/x/mux-session", []rune("ms"), true)
+	scoreOnly, positions, ok := fuzzyMatch("/home/x/mux-session", []rune("ms"), false)
+
+	assert.True(t, ok)
+	assert.Equal(t, score, scoreOnly)
+	assert.Nil(t, positions)
+}
+
+func TestFilterTree_Ranking(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		items []string
+		best  string
+	}{
+		{
+			name:  "word boundaries",
+			query: "ms",
+			items: []string{"/home/x/mux-session", "/home/x/programs", "/home/x/farms"},
+			best:  "/home/x/mux-session",
+		},
+		{
+			name:  "basename",
+			query: "api",
+			items: []string{"/src/api/web", "/src/web/api"},
+			best:  "/src/web/api",
+		},
+		{
+			name:  "camel case",
+			query: "fb",
+			items: []string{"/src/fabric", "/src/fooBar"},
+			best:  "/src/fooBar",
+		},
+		{
+			name:  "fewer gaps",
+			query: "dot",
+			items: []string{"/src/d-o-t-x", "/src/dotfiles", "/src/dxoxt"},
+			best:  "/src/dotfiles",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var items []dataproviders.Item
+			for _, display := range tt.items {
+				items = append(items, dataproviders.Item{Display: display})
+			}
+
+			filtered := FilterTree(items, tt.query, DisplayField)
+
+			assert.Equal(t, tt.best, filtered[len(filtered)-1].Display)
+		})
+	}
+}
+
+func BenchmarkFilterTree(b *testing.B) {
+	items := make([]dataproviders.Item, 10000)
+	for i := range items {
+		items[i] = dataproviders.Item{Display: fmt.Sprintf("%s /home/user/src/github.com/org-%d/project-%d-service", dataproviders.UNSELECTED_ICON, i%50, i)}
+	}
+
+	for _, query := range []string{"ms", "org1proj", "service42"} {
+		b.Run(query, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				FilterTree(items, query, DisplayField)
+			}
+		})
+	}
+}
@@ -1,15 +1,120 @@
 package fzf
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"strings"
 
-type keymap struct{}
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/niedch/mux-session/internal/conf"
+)
+
+// actionHelp describes the actions of the picker in the help line
+var actionHelp = map[string]string{
+	conf.KeyUp:            "up",
+	conf.KeyDown:          "down",
+	conf.KeyPageUp:        "page up",
+	conf.KeyPageDown:      "page down",
+	conf.KeySelect:        "select",
+	conf.KeyMark:          "multi-select",
+	conf.KeyMarkDown:      "multi-select down",
+	conf.KeyToggleFold:    "fold",
+	conf.KeyCollapse:      "collapse",
+	conf.KeyExpand:        "expand",
+	conf.KeyPin:           "pin",
+	conf.KeyKill:          "kill",
+	conf.KeyRename:        "rename",
+	conf.KeyDetach:        "detach others",
+	conf.KeyPrint:         "print ids",
+	conf.KeyTogglePreview: "preview",
+	conf.KeyPreviewUp:     "preview up",
+	conf.KeyPreviewDown:   "preview down",
+	conf.KeyQuit:          "quit",
+}
+
+// keySymbols shorten the names of arrow keys in the help line
+var keySymbols = map[string]string{
+	"up":    "↑",
+	"down":  "↓",
+	"left":  "←",
+	"right": "→",
+}
+
+// keymap binds the actions of the picker to keys. An action without keys is
+// disabled and left out of the help.
+type keymap struct {
+	up            key.Binding
+	down          key.Binding
+	pageUp        key.Binding
+	pageDown      key.Binding
+	selectItem    key.Binding
+	mark          key.Binding
+	markDown      key.Binding
+	toggleFold    key.Binding
+	collapse      key.Binding
+	expand        key.Binding
+	pin           key.Binding
+	kill          key.Binding
+	rename        key.Binding
+	detach        key.Binding
+	print         key.Binding
+	togglePreview key.Binding
+	previewUp     key.Binding
+	previewDown   key.Binding
+	quit          key.Binding
+}
+
+// newKeymap creates the bindings for the keys of every action, as returned
+// by conf.Config.KeyBindings
+func newKeymap(bindings map[string][]string) keymap {
+	binding := func(action string) key.Binding {
+		keys := bindings[action]
+		if len(keys) == 0 {
+			return key.NewBinding(key.WithDisabled())
+		}
+
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = k
+			if symbol, ok := keySymbols[k]; ok {
+				names[i] = symbol
+			}
+		}
+		return key.NewBinding(key.WithKeys(keys...), key.WithHelp(strings.Join(names, "/"), actionHelp[action]))
+	}
+
+	return keymap{
+		up:            binding(conf.KeyUp),
+		down:          binding(conf.KeyDown),
+		pageUp:        binding(conf.KeyPageUp),
+		pageDown:      binding(conf.KeyPageDown),
+		selectItem:    binding(conf.KeySelect),
+		mark:          binding(conf.KeyMark),
+		markDown:      binding(conf.KeyMarkDown),
+		toggleFold:    binding(conf.KeyToggleFold),
+		collapse:      binding(conf.KeyCollapse),
+		expand:        binding(conf.KeyExpand),
+		pin:           binding(conf.KeyPin),
+		kill:          binding(conf.KeyKill),
+		rename:        binding(conf.KeyRename),
+		detach:        binding(conf.KeyDetach),
+		print:         binding(conf.KeyPrint),
+		togglePreview: binding(conf.KeyTogglePreview),
+		previewUp:     binding(conf.KeyPreviewUp),
+		previewDown:   binding(conf.KeyPreviewDown),
+		quit:          binding(conf.KeyQuit),
+	}
+}
+
+// bound reports if the key triggers any action
+func (k keymap) bound(msg tea.KeyMsg) bool {
+	return key.Matches(msg, k.ShortHelp()...)
+}
 
 func (k keymap) ShortHelp() []key.Binding {
 	return []key.Binding{
-		key.NewBinding(key.WithKeys("up", "ctrl+p"), key.WithHelp("↑/ctrl+p", "up")),
-		key.NewBinding(key.WithKeys("down", "ctrl+n"), key.WithHelp("↓/ctrl+n", "down")),
-		key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select")),
-		key.NewBinding(key.WithKeys("esc", "ctrl+c"), key.WithHelp("esc", "quit")),
+		k.up, k.down, k.pageUp, k.pageDown, k.selectItem, k.mark, k.markDown,
+		k.toggleFold, k.collapse, k.expand, k.pin, k.kill, k.rename, k.detach,
+		k.print, k.togglePreview, k.previewUp, k.previewDown, k.quit,
 	}
 }
 
@@ -0,0 +1,107 @@
+package fzf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/help"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/niedch/mux-session/internal/conf"
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeymap_Help(t *testing.T) {
+	config := &conf.Config{Keys: map[string][]string{
+		conf.KeyUp:  {"ctrl+k", "up"},
+		conf.KeyPin: {},
+	}}
+
+	h := help.New()
+	h.Width = 1000
+	view := h.View(newKeymap(config.KeyBindings()))
+
+	assert.Contains(t, view, "ctrl+k/↑ up")
+	assert.Contains(t, view, "↓/ctrl+n down")
+	assert.Contains(t, view, "ctrl+x kill")
+	assert.NotContains(t, view, "pin", "disabled actions are left out")
+}
+
+func TestKeymap_Rebound(t *testing.T) {
+	config := &conf.Config{Keys: map[string][]string{
+		conf.KeyUp:   {"ctrl+k"},
+		conf.KeyKill: {"alt+x"},
+	}}
+
+	m := newActionModel(t, newRecorderWith(t, "api"), "web")
+	m.searchPort.SetKeymap(newKeymap(config.KeyBindings()))
+	require.Equal(t, "web", m.searchPort.GetSelected().Id)
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyUp})
+	assert.Equal(t, "web", m.searchPort.GetSelected().Id, "up is no longer bound")
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyCtrlK})
+	assert.Equal(t, "api", m.searchPort.GetSelected().Id)
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyCtrlX})
+	assert.Nil(t, m.prompt)
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x"), Alt: true})
+	require.NotNil(t, m.prompt)
+	assert.Contains(t, m.searchPort.View(), "Kill session api?")
+}
+
+func TestSearchPort_Page(t *testing.T) {
+	var items []dataproviders.Item
+	for i := range 20 {
+		id := fmt.Sprintf("item-%02d", i)
+		items = append(items, dataproviders.Item{Id: id, Display: id})
+	}
+
+	// Leaves a list of 5 lines
+	sp := newSearchPort(items, func([]dataproviders.Item) {}, DisplayField, 80, 7)
+
+	sp.Update(tea.KeyMsg{Type: tea.KeyShiftUp})
+	assert.Equal(t, "item-14", sp.GetSelected().Id)
+
+	for range 4 {
+		sp.Update(tea.KeyMsg{Type: tea.KeyShiftUp})
+	}
+	assert.Equal(t, "item-00", sp.GetSelected().Id)
+
+	sp.Update(tea.KeyMsg{Type: tea.KeyShiftDown})
+	assert.Equal(t, "item-05", sp.GetSelected().Id)
+}
+
+func TestModel_PagePreview(t *testing.T) {
+	m := newActionModel(t, newRecorderWith(t), "api", "web")
+	m = send(m, tea.WindowSizeMsg{Width: 101, Height: 20})
+	m.previewPort.viewport.SetContent(strings.Repeat("line\n", 50))
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyPgDown})
+	assert.Equal(t, 20, m.previewPort.viewport.YOffset)
+	assert.Equal(t, "web", m.searchPort.GetSelected().Id, "the list does not move")
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyPgUp})
+	assert.Equal(t, 0, m.previewPort.viewport.YOffset)
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyShiftUp})
+	assert.Equal(t, "api", m.searchPort.GetSelected().Id)
+	assert.Equal(t, 0, m.previewPort.viewport.YOffset, "paging the list does not scroll the preview")
+}
+
+func TestModel_TogglePreview(t *testing.T) {
+	m := newActionModel(t, newRecorderWith(t), "web")
+	m = send(m, tea.WindowSizeMsg{Width: 101, Height: 20})
+	assert.Equal(t, 50, m.searchPort.width)
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyCtrlG})
+	assert.True(t, m.previewHidden)
+	assert.Equal(t, 101, m.searchPort.width)
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyCtrlG})
+	assert.False(t, m.previewHidden)
+	assert.Equal(t, 50, m.searchPort.width)
+}
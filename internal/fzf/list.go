@@ -2,6 +2,7 @@ package fzf
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
@@ -9,10 +10,20 @@ import (
 	"github.com/niedch/mux-session/internal/tree"
 )
 
+var (
+	headerStyle = lipgloss.NewStyle().Faint(true)
+	markerStyle = lipgloss.NewStyle().Bold(true)
+)
+
 type listItem struct {
 	text    string
 	index   int
 	matches []int
+	// header is a group title, which cannot be selected
+	header bool
+	pinned bool
+	// hidden counts the subitems left out because the item is collapsed
+	hidden int
 }
 
 type list struct {
@@ -20,11 +31,35 @@ type list struct {
 	displayItems []dataproviders.Item
 	filtered     []listItem
 	cursor       int
+	// cursorMoved is set once the user moves the cursor away from the best
+	// match, so that streamed items do not move it back
+	cursorMoved bool
+	total       int
+	// index maps the Id and Path of an item to its position in items
+	index  map[[2]string]int
+	sorter Sorter
+	field  Field
+	pinned map[string]bool
+	// marked are the items marked for a bulk action, in the order they were
+	// marked
+	marked [][2]string
+	// collapseAll collapses every item with subitems, except the toggled ones
+	collapseAll bool
+	// toggled are the items which the user collapsed or expanded against
+	// collapseAll
+	toggled map[[2]string]bool
+}
+
+// itemKey identifies an item by its Id and Path
+func itemKey(item dataproviders.Item) [2]string {
+	return [2]string{item.Id, item.Path}
 }
 
 func newList(items []dataproviders.Item) *list {
 	l := &list{
 		items: items,
+		total: len(tree.FlattenItems(items)),
+		field: DisplayField,
 	}
 	l.filter("")
 	if len(l.filtered) > 0 {
@@ -33,20 +68,178 @@ func newList(items []dataproviders.Item) *list {
 	return l
 }
 
+// withSorter keeps the items in the order of sorter
+func (l *list) withSorter(sorter Sorter) *list {
+	l.sorter = sorter
+	l.index = nil
+	sorter(l.items)
+	l.filter("")
+	l.cursorToBottom()
+	return l
+}
+
+// withField matches queries against field instead of the display
+func (l *list) withField(field Field) *list {
+	l.field = field
+	l.filter("")
+	l.cursorToBottom()
+	return l
+}
+
+// withCollapsed collapses all items with subitems, e.g. the worktrees of a
+// repository, until the user expands them
+func (l *list) withCollapsed(collapsed bool) *list {
+	l.collapseAll = collapsed
+	l.filter("")
+	l.cursorToBottom()
+	return l
+}
+
+// filter lists the items matching the query. Collapsed items only hide their
+// subitems without a query, so matching subitems are always shown.
 func (l *list) filter(query string) {
 	var itemsToFilter []dataproviders.Item
+	flatten := tree.FlattenItems
 	if query == "" {
 		itemsToFilter = l.items
+		flatten = func(items []dataproviders.Item) []dataproviders.Item {
+			return tree.FlattenItemsCollapsed(items, l.isCollapsed)
+		}
 	} else {
-		itemsToFilter = FilterTree(l.items, query)
+		itemsToFilter = FilterTree(l.items, query, l.field)
+	}
+
+	rest, pinnedItems := GroupPinned(itemsToFilter, l.pinned)
+	l.displayItems = flatten(rest)
+	l.filtered = l.createListItems(l.displayItems, query)
+
+	if len(pinnedItems) == 0 {
+		return
+	}
+
+	offset := len(l.displayItems)
+	pinnedDisplayItems := flatten(pinnedItems)
+	l.displayItems = append(l.displayItems, pinnedDisplayItems...)
+
+	l.filtered = append(l.filtered, listItem{text: PinnedHeader, index: -1, header: true})
+	for _, it := range l.createListItems(pinnedDisplayItems, query) {
+		it.index += offset
+		it.pinned = l.pinned[l.displayItems[it.index].Id]
+		l.filtered = append(l.filtered, it)
+	}
+}
+
+// createListItems creates the list items of the flattened items and counts
+// the subitems hidden by collapsed items
+func (l *list) createListItems(items []dataproviders.Item, query string) []listItem {
+	listItems := createListItems(items, query, l.field)
+	if query != "" {
+		return listItems
+	}
+
+	for i, it := range listItems {
+		if item := items[it.index]; l.isCollapsed(item) {
+			listItems[i].hidden = len(tree.FlattenItems(item.SubItems))
+		}
 	}
+	return listItems
+}
 
-	l.displayItems = tree.FlattenItems(itemsToFilter)
-	l.filtered = createListItems(l.displayItems, query)
+func (l *list) isCollapsed(item dataproviders.Item) bool {
+	return len(item.SubItems) > 0 && l.collapseAll != l.toggled[itemKey(item)]
+}
+
+// setCollapsed collapses or expands the group of the selected item: the item
+// itself if it has subitems, otherwise the item it belongs to. The cursor
+// moves to that item.
+func (l *list) setCollapsed(collapse bool, query string) {
+	group := l.selectedGroup()
+	if group == nil || l.isCollapsed(*group) == collapse {
+		return
+	}
+	l.toggleGroup(*group, query)
+}
+
+// toggleCollapsed collapses the group of the selected item if it is expanded
+// and expands it otherwise
+func (l *list) toggleCollapsed(query string) {
+	if group := l.selectedGroup(); group != nil {
+		l.toggleGroup(*group, query)
+	}
+}
+
+func (l *list) toggleGroup(group dataproviders.Item, query string) {
+	key := itemKey(group)
+	if l.toggled == nil {
+		l.toggled = make(map[[2]string]bool)
+	}
+	if l.toggled[key] {
+		delete(l.toggled, key)
+	} else {
+		l.toggled[key] = true
+	}
+
+	l.filter(query)
+	for i, it := range l.filtered {
+		if !it.header && itemKey(l.displayItems[it.index]) == key {
+			l.cursor = i
+			l.cursorMoved = true
+			return
+		}
+	}
+	l.cursorToBottom()
+}
+
+// selectedGroup returns the selected item if it has subitems, or the item
+// above it which it is a subitem of
+func (l *list) selectedGroup() *dataproviders.Item {
+	selected := l.getSelected()
+	if selected == nil {
+		return nil
+	}
+	if len(selected.SubItems) > 0 {
+		return selected
+	}
+	if selected.TreeLevel == 0 {
+		return nil
+	}
+
+	for i := l.cursor - 1; i >= 0; i-- {
+		if l.filtered[i].header {
+			return nil
+		}
+		if item := &l.displayItems[l.filtered[i].index]; item.TreeLevel < selected.TreeLevel {
+			return item
+		}
+	}
+	return nil
+}
+
+// setPinned changes the pinned items and filters the list again, keeping the
+// cursor on the selected item
+func (l *list) setPinned(pinned map[string]bool, query string) {
+	l.pinned = pinned
+	l.refilter(query)
+}
+
+// matchCount is the number of items matching the query
+func (l *list) matchCount() int {
+	count := 0
+	for _, it := range l.filtered {
+		if !it.header {
+			count++
+		}
+	}
+	return count
 }
 
 func (l *list) updateFilter(query string) {
 	l.filter(query)
+	l.cursorMoved = false
+	l.cursorToBottom()
+}
+
+func (l *list) cursorToBottom() {
 	if len(l.filtered) > 0 {
 		l.cursor = len(l.filtered) - 1
 	} else {
@@ -54,20 +247,188 @@ func (l *list) updateFilter(query string) {
 	}
 }
 
+// addItems inserts streamed items and filters them with the current query. An
+// item with the Id and Path of a listed item replaces it.
+func (l *list) addItems(items []dataproviders.Item, query string) {
+	var selected *dataproviders.Item
+	if current := l.getSelected(); current != nil {
+		item := *current
+		selected = &item
+	}
+
+	if l.index == nil {
+		l.index = make(map[[2]string]int, len(l.items))
+		for i, item := range l.items {
+			l.index[[2]string{item.Id, item.Path}] = i
+		}
+	}
+
+	removed := false
+	for _, item := range items {
+		key := [2]string{item.Id, item.Path}
+		if i, ok := l.index[key]; ok {
+			l.items[i] = item
+			removed = removed || item.Removed
+			continue
+		}
+		if item.Removed {
+			continue
+		}
+		l.index[key] = len(l.items)
+		l.items = append(l.items, item)
+	}
+	if removed {
+		l.items = slices.DeleteFunc(l.items, func(item dataproviders.Item) bool { return item.Removed })
+		clear(l.index)
+	}
+	if l.sorter != nil {
+		l.sorter(l.items)
+	}
+	if removed || l.sorter != nil {
+		for i, item := range l.items {
+			l.index[[2]string{item.Id, item.Path}] = i
+		}
+	}
+	l.total = len(tree.FlattenItems(l.items))
+	l.refilterFrom(selected, query)
+}
+
+// replaceItems replaces all items, keeping the cursor like addItems
+func (l *list) replaceItems(items []dataproviders.Item, query string) {
+	var selected *dataproviders.Item
+	if current := l.getSelected(); current != nil {
+		item := *current
+		selected = &item
+	}
+
+	l.items = items
+	l.index = nil
+	if l.sorter != nil {
+		l.sorter(l.items)
+	}
+	l.total = len(tree.FlattenItems(l.items))
+	l.refilterFrom(selected, query)
+
+	// Items which are gone cannot stay marked
+	var marked [][2]string
+	for _, item := range l.markedItems() {
+		marked = append(marked, itemKey(item))
+	}
+	l.marked = marked
+}
+
+// toggleMarked marks the selected item for a bulk action, or unmarks it
+func (l *list) toggleMarked() {
+	selected := l.getSelected()
+	if selected == nil {
+		return
+	}
+
+	key := itemKey(*selected)
+	if i := slices.Index(l.marked, key); i >= 0 {
+		l.marked = slices.Delete(l.marked, i, i+1)
+		return
+	}
+	l.marked = append(l.marked, key)
+}
+
+func (l *list) isMarked(item dataproviders.Item) bool {
+	return slices.Contains(l.marked, itemKey(item))
+}
+
+// markedItems returns the marked items in the order they were marked
+func (l *list) markedItems() []dataproviders.Item {
+	if len(l.marked) == 0 {
+		return nil
+	}
+
+	byKey := make(map[[2]string]dataproviders.Item, l.total)
+	for _, item := range tree.FlattenItems(l.items) {
+		byKey[itemKey(item)] = item
+	}
+
+	var items []dataproviders.Item
+	for _, key := range l.marked {
+		if item, ok := byKey[key]; ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (l *list) clearMarked() {
+	l.marked = nil
+}
+
+// refilter filters the changed items with the query. The cursor stays on the
+// selected item once the user moved it, and otherwise follows the best match
+// at the bottom.
+func (l *list) refilter(query string) {
+	var selected *dataproviders.Item
+	if current := l.getSelected(); current != nil {
+		item := *current
+		selected = &item
+	}
+
+	l.refilterFrom(selected, query)
+}
+
+func (l *list) refilterFrom(selected *dataproviders.Item, query string) {
+	l.filter(query)
+
+	if l.cursorMoved && selected != nil {
+		for i, it := range l.filtered {
+			if it.header {
+				continue
+			}
+			displayItem := l.displayItems[it.index]
+			if displayItem.Id == selected.Id && displayItem.Path == selected.Path {
+				l.cursor = i
+				return
+			}
+		}
+	}
+
+	l.cursorMoved = false
+	l.cursorToBottom()
+}
+
+// moveUp moves the cursor to the item above, skipping group headers
 func (l *list) moveUp() {
-	if l.cursor > 0 {
-		l.cursor--
+	for i := l.cursor - 1; i >= 0; i-- {
+		if !l.filtered[i].header {
+			l.cursor = i
+			l.cursorMoved = true
+			return
+		}
 	}
 }
 
+// moveDown moves the cursor to the item below, skipping group headers
 func (l *list) moveDown() {
-	if l.cursor < len(l.filtered)-1 {
-		l.cursor++
+	for i := l.cursor + 1; i < len(l.filtered); i++ {
+		if !l.filtered[i].header {
+			l.cursor = i
+			l.cursorMoved = true
+			return
+		}
+	}
+}
+
+// moveBy moves the cursor n items down, or up for a negative n, stopping at
+// the first and last item
+func (l *list) moveBy(n int) {
+	for range max(n, -n) {
+		if n < 0 {
+			l.moveUp()
+		} else {
+			l.moveDown()
+		}
 	}
 }
 
 func (l *list) getSelected() *dataproviders.Item {
-	if len(l.filtered) > 0 && l.cursor >= 0 && l.cursor < len(l.filtered) {
+	if len(l.filtered) > 0 && l.cursor >= 0 && l.cursor < len(l.filtered) && !l.filtered[l.cursor].header {
 		return &l.displayItems[l.filtered[l.cursor].index]
 	}
 	return nil
@@ -97,14 +458,21 @@ func (l *list) renderItem(i int) string {
 	}
 
 	it := l.filtered[i]
+	if it.header {
+		return headerStyle.Render(it.text) + "\n"
+	}
 	isSelected := i == l.cursor
 
-	cursor := "  "
+	cursor, marker := " ", " "
 	style := lipgloss.NewStyle()
 	if isSelected {
-		cursor = "> "
+		cursor = ">"
 		style = style.Bold(true)
 	}
+	if l.isMarked(l.displayItems[it.index]) {
+		marker = markerStyle.Render("+")
+	}
+	cursor += marker
 
 	var highlightedText strings.Builder
 	matchStyle := style.Copy().Bold(true)
@@ -122,5 +490,14 @@ func (l *list) renderItem(i int) string {
 		}
 	}
 
-	return fmt.Sprintf("%s%s\n", cursor, highlightedText.String())
+	if it.pinned {
+		cursor += dataproviders.PINNED_ICON + " "
+	}
+
+	badge := ""
+	if it.hidden > 0 {
+		badge = headerStyle.Render(fmt.Sprintf(" (+%d)", it.hidden))
+	}
+
+	return fmt.Sprintf("%s%s%s\n", cursor, highlightedText.String(), badge)
 }
@@ -0,0 +1,222 @@
+package fzf
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList_AddItems(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		initial     []dataproviders.Item
+		added       []dataproviders.Item
+		moveUp      int
+		expected    string
+		expectedLen int
+		total       int
+	}{
+		{
+			name:        "keeps the query applied",
+			query:       "api",
+			initial:     []dataproviders.Item{{Id: "api", Display: "api"}, {Id: "web", Display: "web"}},
+			added:       []dataproviders.Item{{Id: "api-v2", Display: "api-v2"}, {Id: "docs", Display: "docs"}},
+			expected:    "api-v2",
+			expectedLen: 2,
+			total:       4,
+		},
+		{
+			name:        "cursor follows the best match",
+			initial:     []dataproviders.Item{{Id: "a", Display: "a"}},
+			added:       []dataproviders.Item{{Id: "b", Display: "b"}, {Id: "c", Display: "c"}},
+			expected:    "c",
+			expectedLen: 3,
+			total:       3,
+		},
+		{
+			name:        "cursor moved by the user stays on its item",
+			initial:     []dataproviders.Item{{Id: "a", Display: "a"}, {Id: "b", Display: "b"}},
+			added:       []dataproviders.Item{{Id: "c", Display: "c"}},
+			moveUp:      1,
+			expected:    "a",
+			expectedLen: 3,
+			total:       3,
+		},
+		{
+			name:        "sub items are counted",
+			initial:     nil,
+			added:       []dataproviders.Item{{Id: "repo", Display: "repo", SubItems: []dataproviders.Item{{Id: "feature", Display: "feature"}}}},
+			expected:    "feature",
+			expectedLen: 2,
+			total:       2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newList(tt.initial)
+			l.updateFilter(tt.query)
+			for range tt.moveUp {
+				l.moveUp()
+			}
+
+			l.addItems(tt.added, tt.query)
+
+			require.NotNil(t, l.getSelected())
+			assert.Equal(t, tt.expected, l.getSelected().Id)
+			assert.Len(t, l.filtered, tt.expectedLen)
+			assert.Equal(t, tt.total, l.total)
+		})
+	}
+}
+
+func TestList_UpdateFilterResetsMovedCursor(t *testing.T) {
+	l := newList([]dataproviders.Item{{Id: "a", Display: "a"}, {Id: "b", Display: "b"}})
+	l.moveUp()
+	l.updateFilter("")
+
+	l.addItems([]dataproviders.Item{{Id: "c", Display: "c"}}, "")
+
+	assert.Equal(t, "c", l.getSelected().Id)
+}
+
+func TestList_AddItemsReplacesKnownItems(t *testing.T) {
+	l := newList([]dataproviders.Item{{Id: "a", Path: "/src/a", Display: "a"}, {Id: "b", Path: "/src/b", Display: "b"}})
+
+	l.addItems([]dataproviders.Item{{Id: "a", Path: "/src/a", Display: "a (worktrees)"}}, "")
+
+	assert.Len(t, l.items, 2)
+	assert.Equal(t, "a (worktrees)", l.items[0].Display)
+	assert.Equal(t, 2, l.total)
+}
+
+func TestList_AddItemsTakesBackRemovedItems(t *testing.T) {
+	l := newList([]dataproviders.Item{{Id: "a", Path: "/src/a", Display: "a"}, {Id: "b", Path: "/src/b", Display: "b"}, {Id: "c", Path: "/src/c", Display: "c"}})
+
+	l.addItems([]dataproviders.Item{
+		{Id: "a", Path: "/src/a", Removed: true},
+		{Id: "d", Path: "/src/d", Removed: true},
+	}, "")
+
+	assert.Equal(t, []string{"b", "c"}, itemIds(l.items))
+	assert.Equal(t, 2, l.total)
+
+	l.addItems([]dataproviders.Item{{Id: "c", Path: "/src/c", Display: "c (changed)"}}, "")
+	assert.Equal(t, "c (changed)", l.items[1].Display, "the index should follow the removal")
+}
+
+func TestList_ToggleMarked(t *testing.T) {
+	l := newList([]dataproviders.Item{{Id: "a", Display: "a"}, {Id: "b", Display: "b"}, {Id: "c", Display: "c"}})
+
+	l.toggleMarked()
+	l.moveUp()
+	l.moveUp()
+	l.toggleMarked()
+	assert.Equal(t, []string{"c", "a"}, ids(l.markedItems()), "marked items keep the order they were marked in")
+	assert.Contains(t, l.renderItem(0), ">+")
+	assert.Contains(t, l.renderItem(2), " +")
+
+	l.toggleMarked()
+	assert.Equal(t, []string{"c"}, ids(l.markedItems()))
+
+	l.updateFilter("b")
+	assert.Equal(t, []string{"c"}, ids(l.markedItems()), "filtering keeps items marked")
+
+	l.replaceItems([]dataproviders.Item{{Id: "a", Display: "a"}, {Id: "b", Display: "b"}}, "b")
+	assert.Empty(t, l.markedItems(), "removed items are unmarked")
+}
+
+// worktreeItems is a repository with two worktrees below a plain project
+func worktreeItems() []dataproviders.Item {
+	return []dataproviders.Item{
+		{Id: "repo", Display: "repo", Path: "/src/repo", IsWorktree: true, SubItems: []dataproviders.Item{
+			{Id: "fix", Display: "fix", Path: "/src/repo-fix", TreeLevel: 1, ParentId: "repo"},
+			{Id: "feature", Display: "feature", Path: "/src/repo-feature", TreeLevel: 1, ParentId: "repo"},
+		}},
+		{Id: "api", Display: "api", Path: "/src/api"},
+	}
+}
+
+func TestList_Collapse(t *testing.T) {
+	l := newList(worktreeItems()).withCollapsed(true)
+	assert.Equal(t, []string{"repo", "api"}, ids(l.displayItems))
+	assert.Contains(t, l.renderItem(0), "repo (+2)")
+	assert.Equal(t, "api", l.getSelected().Id)
+
+	// Only items with subitems can be collapsed or expanded
+	l.toggleCollapsed("")
+	assert.Equal(t, []string{"repo", "api"}, ids(l.displayItems))
+
+	l.moveUp()
+	l.setCollapsed(false, "")
+	assert.Equal(t, []string{"repo", "fix", "feature", "api"}, ids(l.displayItems))
+	assert.NotContains(t, l.renderItem(0), "(+2)")
+
+	// Collapsing from a subitem collapses its parent and selects it
+	l.moveDown()
+	l.moveDown()
+	require.Equal(t, "feature", l.getSelected().Id)
+	l.setCollapsed(true, "")
+	assert.Equal(t, []string{"repo", "api"}, ids(l.displayItems))
+	assert.Equal(t, "repo", l.getSelected().Id)
+
+	l.toggleCollapsed("")
+	assert.Equal(t, []string{"repo", "fix", "feature", "api"}, ids(l.displayItems))
+	l.toggleCollapsed("")
+
+	// A query shows the matching subitems of collapsed items
+	l.updateFilter("fix")
+	assert.Equal(t, []string{"repo", "fix"}, ids(l.displayItems))
+	assert.NotContains(t, l.renderItem(0), "(+")
+
+	l.updateFilter("")
+	assert.Equal(t, []string{"repo", "api"}, ids(l.displayItems))
+
+	// The state of an item survives reloading the items
+	l.replaceItems(worktreeItems(), "")
+	assert.Equal(t, []string{"repo", "api"}, ids(l.displayItems))
+}
+
+func TestSearchPort_FoldWhileSearching(t *testing.T) {
+	sp := newSearchPort(worktreeItems(), func([]dataproviders.Item) {}, DisplayField, 80, 20)
+	sp.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("fix")})
+
+	sp.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	assert.Contains(t, sp.View(), "Clear the search to fold worktrees")
+
+	sp.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	require.Empty(t, sp.textInput.Value())
+	assert.Equal(t, []string{"repo", "fix", "feature", "api"}, ids(sp.list.displayItems), "the fold was ignored")
+}
+
+func TestSearchPort_CollapseKeys(t *testing.T) {
+	sp := newSearchPort(worktreeItems(), func([]dataproviders.Item) {}, DisplayField, 80, 20)
+	sp.list.moveUp()
+	require.Equal(t, "feature", sp.GetSelected().Id)
+
+	sp.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	assert.Equal(t, []string{"repo", "api"}, ids(sp.list.displayItems))
+
+	sp.Update(tea.KeyMsg{Type: tea.KeyRight})
+	assert.Equal(t, []string{"repo", "fix", "feature", "api"}, ids(sp.list.displayItems))
+
+	sp.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	assert.Equal(t, []string{"repo", "api"}, ids(sp.list.displayItems))
+
+	// With a query the arrows move the cursor of the search input
+	sp.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("re")})
+	sp.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	assert.Equal(t, 1, sp.textInput.Position())
+}
+
+func ids(items []dataproviders.Item) []string {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.Id)
+	}
+	return ids
+}
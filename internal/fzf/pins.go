@@ -0,0 +1,24 @@
+package fzf
+
+import "github.com/niedch/mux-session/internal/dataproviders"
+
+// PinnedHeader titles the group of pinned items at the bottom of the list
+const PinnedHeader = "Pinned"
+
+// GroupPinned splits the items into the unpinned and the pinned ones, both
+// keeping their order. The pinned ones are listed last, next to the cursor.
+func GroupPinned(items []dataproviders.Item, pinned map[string]bool) (rest []dataproviders.Item, pinnedItems []dataproviders.Item) {
+	if len(pinned) == 0 {
+		return items, nil
+	}
+
+	for _, item := range items {
+		if pinned[item.Id] {
+			pinnedItems = append(pinnedItems, item)
+		} else {
+			rest = append(rest, item)
+		}
+	}
+
+	return rest, pinnedItems
+}
@@ -0,0 +1,97 @@
+package fzf
+
+import (
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/niedch/mux-session/internal/pins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listTexts(l *list) []string {
+	var texts []string
+	for _, it := range l.filtered {
+		texts = append(texts, it.text)
+	}
+	return texts
+}
+
+func TestList_PinnedGroup(t *testing.T) {
+	l := newList([]dataproviders.Item{
+		{Id: "api", Display: "api"},
+		{Id: "docs", Display: "docs"},
+		{Id: "web", Display: "web"},
+	})
+
+	l.setPinned(map[string]bool{"api": true}, "")
+
+	assert.Equal(t, []string{"docs", "web", PinnedHeader, "api"}, listTexts(l))
+	assert.Equal(t, 3, l.matchCount())
+	require.NotNil(t, l.getSelected())
+	assert.Equal(t, "api", l.getSelected().Id, "the cursor starts on the pinned item")
+	assert.True(t, l.filtered[3].pinned)
+
+	l.moveUp()
+	assert.Equal(t, "web", l.getSelected().Id, "the header is skipped")
+	l.moveDown()
+	assert.Equal(t, "api", l.getSelected().Id)
+
+	t.Run("pinned items stay at the bottom while filtering", func(t *testing.T) {
+		l.updateFilter("a")
+		assert.Equal(t, []string{PinnedHeader, "api"}, listTexts(l))
+
+		l.moveUp()
+		assert.Equal(t, "api", l.getSelected().Id, "the cursor cannot move onto the header")
+	})
+
+	t.Run("unpinning removes the group", func(t *testing.T) {
+		l.updateFilter("")
+		l.setPinned(map[string]bool{}, "")
+		assert.Equal(t, []string{"api", "docs", "web"}, listTexts(l))
+	})
+}
+
+func TestList_SetPinnedKeepsMovedCursor(t *testing.T) {
+	l := newList([]dataproviders.Item{{Id: "api", Display: "api"}, {Id: "web", Display: "web"}})
+	l.moveUp()
+
+	l.setPinned(map[string]bool{"api": true}, "")
+
+	assert.Equal(t, "api", l.getSelected().Id)
+}
+
+func TestGroupPinned(t *testing.T) {
+	items := []dataproviders.Item{{Id: "a"}, {Id: "b"}, {Id: "c"}, {Id: "d"}}
+
+	rest, pinned := GroupPinned(items, map[string]bool{"c": true, "a": true})
+
+	assert.Equal(t, []string{"b", "d"}, itemIds(rest))
+	assert.Equal(t, []string{"a", "c"}, itemIds(pinned), "pinned items keep the sort order")
+}
+
+func TestModel_PinWorktreePinsItsRepository(t *testing.T) {
+	items := []dataproviders.Item{
+		{Id: "web", Display: "web", Path: "/src/web"},
+		{Id: "api", Display: "api", Path: "/src/api", IsWorktree: true, SubItems: []dataproviders.Item{
+			{Id: "feature", Display: "feature", Path: "/src/feature", ParentId: "api", TreeLevel: 1},
+		}},
+	}
+	store := pins.NewStore(filepath.Join(t.TempDir(), "pins"))
+	m := model{
+		searchPort:  newSearchPort(items, func([]dataproviders.Item) {}, DisplayField, 80, 20),
+		previewPort: newPreviewPort(emptyPreview{}, 80, 20),
+		pins:        store,
+	}
+	require.Equal(t, "feature", m.searchPort.GetSelected().Id)
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyCtrlS})
+
+	ids, err := store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api"}, ids)
+	assert.Equal(t, "Pinned api, the repository of worktree feature", m.searchPort.status)
+	assert.Equal(t, []string{"web", PinnedHeader, "api", " └── feature"}, listTexts(m.searchPort.list))
+}
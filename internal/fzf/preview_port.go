@@ -77,6 +77,16 @@ func (p *previewPort) Update(msg tea.Msg) tea.Cmd {
 	return cmd
 }
 
+// PageUp scrolls the preview up by its height
+func (p *previewPort) PageUp() {
+	p.viewport.ViewUp()
+}
+
+// PageDown scrolls the preview down by its height
+func (p *previewPort) PageDown() {
+	p.viewport.ViewDown()
+}
+
 func (p *previewPort) View() string {
 	return p.viewport.View()
 }
@@ -0,0 +1,242 @@
+package fzf
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/niedch/mux-session/internal/conf"
+	"github.com/niedch/mux-session/internal/dataproviders"
+)
+
+// Field returns the text of an item which queries are matched against
+type Field func(item dataproviders.Item) string
+
+// DisplayField matches queries against what the picker shows, icons included
+func DisplayField(item dataproviders.Item) string {
+	return item.Display
+}
+
+// NewField returns the Field for the search_field setting of the config
+func NewField(config *conf.Config) Field {
+	switch config.SearchField {
+	case conf.SearchFieldPath:
+		return func(item dataproviders.Item) string { return item.Path }
+	case conf.SearchFieldId:
+		return func(item dataproviders.Item) string { return item.Id }
+	default:
+		return DisplayField
+	}
+}
+
+type termKind int
+
+const (
+	termFuzzy termKind = iota
+	termExact
+	termPrefix
+	termSuffix
+	termEqual
+)
+
+// searchTerm is a single word of a query. A term with a key is matched
+// against the metadata of an item instead of its text.
+type searchTerm struct {
+	kind   termKind
+	key    string
+	text   []rune
+	negate bool
+}
+
+// searchQuery is a query in the extended search syntax of fzf. Every group
+// has to match, a group matches if one of its terms does.
+type searchQuery [][]searchTerm
+
+// parseQuery splits the query into terms at spaces, "\ " is a literal space.
+// A term is matched fuzzily unless it is prefixed with ' for an exact match,
+// ^ for a prefix or suffixed with $ for a suffix. A term prefixed with ! must
+// not be contained in the text. Terms joined by " | " are alternatives.
+// A key:value term matches items with a metadata value of key starting with
+// value, or equal to it with a trailing $.
+func parseQuery(query string) searchQuery {
+	query = strings.ReplaceAll(strings.ToLower(query), `\ `, "\x00")
+
+	var q searchQuery
+	or := false
+	for _, word := range strings.Fields(query) {
+		if word == "|" {
+			or = len(q) > 0
+			continue
+		}
+
+		term, ok := parseTerm(strings.ReplaceAll(word, "\x00", " "))
+		if !ok {
+			continue
+		}
+
+		if or {
+			q[len(q)-1] = append(q[len(q)-1], term)
+		} else {
+			q = append(q, []searchTerm{term})
+		}
+		or = false
+	}
+
+	return q
+}
+
+func parseTerm(word string) (searchTerm, bool) {
+	term := searchTerm{kind: termFuzzy}
+
+	if rest, ok := strings.CutPrefix(word, "!"); ok {
+		// Like fzf, a negated term is matched exactly
+		term.negate, term.kind, word = true, termExact, rest
+	}
+
+	if key, value, ok := strings.Cut(word, ":"); ok && isMetadataKey(key) {
+		term.key, term.kind, word = key, termPrefix, value
+		if rest, ok := strings.CutSuffix(word, "$"); ok {
+			term.kind, word = termEqual, rest
+		}
+		if word == "" {
+			return searchTerm{}, false
+		}
+		term.text = []rune(word)
+		return term, true
+	}
+
+	if rest, ok := strings.CutPrefix(word, "'"); ok {
+		term.kind, word = termExact, rest
+	} else if rest, ok := strings.CutPrefix(word, "^"); ok {
+		term.kind, word = termPrefix, rest
+	}
+
+	if rest, ok := strings.CutSuffix(word, "$"); ok {
+		if term.kind == termPrefix {
+			term.kind = termEqual
+		} else {
+			term.kind = termSuffix
+		}
+		word = rest
+	}
+
+	// A bare operator, e.g. while it is being typed, filters nothing
+	if word == "" {
+		return searchTerm{}, false
+	}
+
+	term.text = []rune(word)
+	return term, true
+}
+
+// metadataKeys are the keys of item metadata a term can filter on
+var metadataKeys = []string{
+	dataproviders.MetadataBranch,
+	dataproviders.MetadataLang,
+	dataproviders.MetadataTag,
+	dataproviders.MetadataSession,
+}
+
+// isMetadataKey reports if key, the part of a term before a colon, is a
+// metadata key. Other terms with a colon, like foo:bar or a URL, are matched
+// against the text.
+func isMetadataKey(key string) bool {
+	return slices.Contains(metadataKeys, key)
+}
+
+func (t searchTerm) match(item dataproviders.Item, text string, withPositions bool) (int, []int, bool) {
+	if t.key != "" {
+		return 0, nil, t.matchMetadata(item)
+	}
+
+	switch t.kind {
+	case termExact:
+		return exactMatch(text, t.text, false, false, withPositions)
+	case termPrefix:
+		return exactMatch(text, t.text, true, false, withPositions)
+	case termSuffix:
+		return exactMatch(text, t.text, false, true, withPositions)
+	case termEqual:
+		return exactMatch(text, t.text, true, true, withPositions)
+	default:
+		return fuzzyMatch(text, t.text, withPositions)
+	}
+}
+
+// matchMetadata reports if a metadata value of the term's key matches, case
+// is ignored
+func (t searchTerm) matchMetadata(item dataproviders.Item) bool {
+	text := string(t.text)
+	for _, value := range item.Metadata[t.key] {
+		value = strings.ToLower(value)
+		if value == text || (t.kind == termPrefix && strings.HasPrefix(value, text)) {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the summed score of the matching terms and the byte offsets
+// of their matched characters in text, the field of item, or false if the
+// item does not match. An empty query matches everything.
+func (q searchQuery) match(item dataproviders.Item, text string) (int, []int, bool) {
+	return q.matchTerms(item, text, true)
+}
+
+// score is match without the positions, which filtering runs for every item
+// on every key press
+func (q searchQuery) score(item dataproviders.Item, text string) (int, bool) {
+	total, _, ok := q.matchTerms(item, text, false)
+	return total, ok
+}
+
+func (q searchQuery) matchTerms(item dataproviders.Item, text string, withPositions bool) (int, []int, bool) {
+	total := 0
+	var positions []int
+
+	for _, group := range q {
+		matched := false
+		for _, term := range group {
+			score, termPositions, ok := term.match(item, text, withPositions)
+			if term.negate {
+				if !ok {
+					matched = true
+					break
+				}
+				continue
+			}
+			if ok {
+				total += score
+				positions = append(positions, termPositions...)
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return 0, nil, false
+		}
+	}
+
+	slices.Sort(positions)
+	return total, slices.Compact(positions), true
+}
+
+// displayPositions maps positions in the matched field of an item to its
+// display, which shows the field after an icon. Positions in a field which
+// is not shown are dropped.
+func displayPositions(display, field string, positions []int) []int {
+	if len(positions) == 0 || display == field {
+		return positions
+	}
+
+	offset := strings.LastIndex(display, field)
+	if offset < 0 {
+		return nil
+	}
+
+	shifted := make([]int, len(positions))
+	for i, position := range positions {
+		shifted[i] = position + offset
+	}
+	return shifted
+}
@@ -0,0 +1,186 @@
+package fzf
+
+import (
+	"testing"
+
+	"github.com/niedch/mux-session/internal/conf"
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected searchQuery
+	}{
+		{query: "", expected: nil},
+		{query: "api web", expected: searchQuery{
+			{{kind: termFuzzy, text: []rune("api")}},
+			{{kind: termFuzzy, text: []rune("web")}},
+		}},
+		{query: "'Exact ^pre suf$ ^whole$", expected: searchQuery{
+			{{kind: termExact, text: []rune("exact")}},
+			{{kind: termPrefix, text: []rune("pre")}},
+			{{kind: termSuffix, text: []rune("suf")}},
+			{{kind: termEqual, text: []rune("whole")}},
+		}},
+		{query: "!test !^old", expected: searchQuery{
+			{{kind: termExact, text: []rune("test"), negate: true}},
+			{{kind: termPrefix, text: []rune("old"), negate: true}},
+		}},
+		{query: "^core go$ | rb$ | py$", expected: searchQuery{
+			{{kind: termPrefix, text: []rune("core")}},
+			{{kind: termSuffix, text: []rune("go")}, {kind: termSuffix, text: []rune("rb")}, {kind: termSuffix, text: []rune("py")}},
+		}},
+		{query: `my\ project`, expected: searchQuery{
+			{{kind: termFuzzy, text: []rune("my project")}},
+		}},
+		{query: "api ! ' ^ | $", expected: searchQuery{
+			{{kind: termFuzzy, text: []rune("api")}},
+		}},
+		{query: "Lang:Go !tag:old$ branch: v1.2:rc", expected: searchQuery{
+			{{kind: termPrefix, key: "lang", text: []rune("go")}},
+			{{kind: termEqual, key: "tag", text: []rune("old"), negate: true}},
+			{{kind: termFuzzy, text: []rune("v1.2:rc")}},
+		}},
+		{query: "foo:bar", expected: searchQuery{
+			{{kind: termFuzzy, text: []rune("foo:bar")}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseQuery(tt.query))
+		})
+	}
+}
+
+func TestFilterTree_ExtendedSearch(t *testing.T) {
+	items := []dataproviders.Item{
+		{Id: "api", Display: "api"},
+		{Id: "api-test", Display: "api-test"},
+		{Id: "web-api", Display: "web-api"},
+		{Id: "webapp", Display: "webapp"},
+		{Id: "docs", Display: "docs"},
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{query: "ap", expected: []string{"api", "api-test", "web-api", "webapp"}},
+		{query: "'pi-", expected: []string{"api-test"}},
+		{query: "^api", expected: []string{"api-test", "api"}},
+		{query: "api$", expected: []string{"api", "web-api"}},
+		{query: "^api$", expected: []string{"api"}},
+		{query: "api !test", expected: []string{"api", "web-api"}},
+		{query: "!api", expected: []string{"webapp", "docs"}},
+		{query: "^docs | ^webapp", expected: []string{"webapp", "docs"}},
+		{query: "api !^web | test", expected: []string{"api", "api-test"}},
+		{query: "'ip", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.expected, itemIds(FilterTree(items, tt.query, DisplayField)))
+		})
+	}
+
+	// A colon after a word which is not a metadata key is matched as text
+	notes := []dataproviders.Item{{Id: "foo:bar", Display: "foo:bar"}, {Id: "foo", Display: "foo"}}
+	assert.Equal(t, []string{"foo:bar"}, itemIds(FilterTree(notes, "foo:bar", DisplayField)))
+}
+
+func TestFilterTree_Metadata(t *testing.T) {
+	items := []dataproviders.Item{
+		{Id: "api", Display: "api", Metadata: map[string][]string{
+			dataproviders.MetadataBranch:  {"feature/x"},
+			dataproviders.MetadataLang:    {"go"},
+			dataproviders.MetadataTag:     {"backend", "billing"},
+			dataproviders.MetadataSession: {dataproviders.SessionRunning},
+		}},
+		{Id: "web", Display: "web", Metadata: map[string][]string{
+			dataproviders.MetadataBranch: {"main"},
+			dataproviders.MetadataLang:   {"typescript", "javascript"},
+		}, SubItems: []dataproviders.Item{
+			{Id: "web-fix", Display: "web-fix", ParentId: "web", Metadata: map[string][]string{
+				dataproviders.MetadataBranch: {"feature/fix"},
+			}},
+		}},
+		{Id: "docs", Display: "docs"},
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{query: "lang:go", expected: []string{"api"}},
+		{query: "LANG:Java", expected: []string{"web"}},
+		{query: "lang:java$", expected: nil},
+		{query: "tag:billing", expected: []string{"api"}},
+		{query: "branch:feature/", expected: []string{"api", "web"}},
+		{query: "session:running", expected: []string{"api"}},
+		{query: "!session:running", expected: []string{"web", "docs"}},
+		{query: "lang:go | tag:docs", expected: []string{"api"}},
+		{query: "branch:main we", expected: []string{"web"}},
+		{query: "unknown:x", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.expected, itemIds(FilterTree(items, tt.query, DisplayField)))
+		})
+	}
+
+	// A parent kept for its children only lists the matching ones
+	filtered := FilterTree(items, "branch:feature/fix", DisplayField)
+	assert.Equal(t, []string{"web"}, itemIds(filtered))
+	assert.Equal(t, []string{"web-fix"}, itemIds(filtered[0].SubItems))
+}
+
+func TestFilterTree_Field(t *testing.T) {
+	items := []dataproviders.Item{
+		{Id: "mux-session", Path: "/home/x/src/mux-session", Display: dataproviders.UNSELECTED_ICON + " /home/x/src/mux-session"},
+		{Id: "src", Path: "/srv/src", Display: dataproviders.UNSELECTED_ICON + " /srv/src"},
+	}
+
+	tests := []struct {
+		field    string
+		query    string
+		expected []string
+	}{
+		{field: conf.SearchFieldDisplay, query: "^mux", expected: nil},
+		{field: conf.SearchFieldId, query: "^mux", expected: []string{"mux-session"}},
+		{field: conf.SearchFieldId, query: "src", expected: []string{"src"}},
+		{field: conf.SearchFieldPath, query: "src", expected: []string{"mux-session", "src"}},
+		{field: conf.SearchFieldPath, query: "^/srv", expected: []string{"src"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field+" "+tt.query, func(t *testing.T) {
+			field := NewField(&conf.Config{SearchField: tt.field})
+
+			assert.ElementsMatch(t, tt.expected, itemIds(FilterTree(items, tt.query, field)))
+		})
+	}
+}
+
+func TestCreateListItems_HighlightsField(t *testing.T) {
+	items := []dataproviders.Item{
+		{Id: "api", Path: "/src/api", Display: "* /src/api"},
+		{Id: "feature", Path: "/worktrees/feature", Display: "* feature"},
+	}
+	idField := NewField(&conf.Config{SearchField: conf.SearchFieldId})
+	pathField := NewField(&conf.Config{SearchField: conf.SearchFieldPath})
+
+	assert.Equal(t, []int{8, 9}, createListItems(items, "'pi", idField)[0].matches)
+	assert.Equal(t, []int{2, 3, 4}, createListItems(items, "^/sr", pathField)[0].matches)
+	assert.Nil(t, createListItems(items, "work", pathField)[1].matches, "the path of a worktree is not shown")
+}
+
+func TestExactMatch_BestOccurrence(t *testing.T) {
+	_, positions, ok := exactMatch("/src/api-client/api", []rune("api"), false, false, true)
+
+	assert.True(t, ok)
+	assert.Equal(t, []int{16, 17, 18}, positions, "the basename scores best")
+}
@@ -1,11 +1,16 @@
 package fzf
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/niedch/mux-session/internal/conf"
 	"github.com/niedch/mux-session/internal/dataproviders"
 )
 
@@ -14,11 +19,17 @@ type searchPort struct {
 	help      help.Model
 	keymap    keymap
 	list      *list
-	width     int
-	height    int
+	spinner   spinner.Model
+	loading   bool
+	// prompt replaces the search input while an action asks for input
+	prompt string
+	// status is a message about the last action, shown until the next key
+	status string
+	width  int
+	height int
 }
 
-func newSearchPort(items []dataproviders.Item, width, height int) *searchPort {
+func newSearchPort(items []dataproviders.Item, sorter Sorter, field Field, width, height int) *searchPort {
 	ti := textinput.New()
 	ti.Placeholder = "search..."
 	ti.Focus()
@@ -26,13 +37,14 @@ func newSearchPort(items []dataproviders.Item, width, height int) *searchPort {
 	ti.Width = 20
 
 	h := help.New()
-	km := keymap{}
+	km := newKeymap(conf.DefaultKeys)
 
 	return &searchPort{
 		textInput: ti,
 		help:      h,
 		keymap:    km,
-		list:      newList(items),
+		list:      newList(items).withSorter(sorter).withField(field),
+		spinner:   spinner.New(spinner.WithSpinner(spinner.MiniDot)),
 		width:     width,
 		height:    height,
 	}
@@ -40,14 +52,52 @@ func newSearchPort(items []dataproviders.Item, width, height int) *searchPort {
 
 func (sp *searchPort) Update(msg tea.Msg) tea.Cmd {
 	switch msg := msg.(type) {
+	case spinner.TickMsg:
+		if !sp.loading {
+			return nil
+		}
+		var cmd tea.Cmd
+		sp.spinner, cmd = sp.spinner.Update(msg)
+		return cmd
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "up", "ctrl+p":
+		sp.status = ""
+		switch {
+		case key.Matches(msg, sp.keymap.up):
 			sp.list.moveUp()
 			return nil
-		case "down", "ctrl+n":
+		case key.Matches(msg, sp.keymap.down):
 			sp.list.moveDown()
 			return nil
+		case key.Matches(msg, sp.keymap.pageUp):
+			sp.list.moveBy(-sp.listHeight())
+			return nil
+		case key.Matches(msg, sp.keymap.pageDown):
+			sp.list.moveBy(sp.listHeight())
+			return nil
+		case key.Matches(msg, sp.keymap.mark):
+			sp.list.toggleMarked()
+			sp.list.moveUp()
+			return nil
+		case key.Matches(msg, sp.keymap.markDown):
+			sp.list.toggleMarked()
+			sp.list.moveDown()
+			return nil
+		case key.Matches(msg, sp.keymap.toggleFold):
+			// A query lists the matching subitems of collapsed items anyway,
+			// so a fold would only show once the query is cleared
+			if sp.textInput.Value() != "" {
+				sp.status = "Clear the search to fold worktrees"
+				return nil
+			}
+			sp.list.toggleCollapsed("")
+			return nil
+		case key.Matches(msg, sp.keymap.collapse, sp.keymap.expand):
+			// The arrows move the cursor of the search input while there
+			// is a query
+			if sp.textInput.Value() == "" {
+				sp.list.setCollapsed(key.Matches(msg, sp.keymap.collapse), "")
+				return nil
+			}
 		}
 	}
 
@@ -61,6 +111,61 @@ func (sp *searchPort) Update(msg tea.Msg) tea.Cmd {
 	return cmd
 }
 
+// StartLoading shows the spinner until StopLoading is called
+func (sp *searchPort) StartLoading() tea.Cmd {
+	sp.loading = true
+	return sp.spinner.Tick
+}
+
+func (sp *searchPort) StopLoading() {
+	sp.loading = false
+}
+
+// SetPinned changes which items are listed in the pinned group
+func (sp *searchPort) SetPinned(pinned map[string]bool) {
+	sp.list.setPinned(pinned, sp.textInput.Value())
+}
+
+// SetKeymap changes the keys of the actions
+func (sp *searchPort) SetKeymap(km keymap) {
+	sp.keymap = km
+}
+
+// SetCollapsed collapses all items with subitems, or expands them
+func (sp *searchPort) SetCollapsed(collapsed bool) {
+	sp.list.withCollapsed(collapsed)
+}
+
+// SetPrompt shows the prompt instead of the search input, an empty prompt
+// shows the search input again
+func (sp *searchPort) SetPrompt(prompt string) {
+	sp.prompt = prompt
+}
+
+// SetStatus shows the message in place of the key help until the next key
+func (sp *searchPort) SetStatus(message string) {
+	sp.status = message
+}
+
+// ReplaceItems replaces all items, e.g. after they were reloaded
+func (sp *searchPort) ReplaceItems(items []dataproviders.Item) {
+	sp.list.replaceItems(items, sp.textInput.Value())
+}
+
+// MarkedItems returns the items marked with tab, in the order they were marked
+func (sp *searchPort) MarkedItems() []dataproviders.Item {
+	return sp.list.markedItems()
+}
+
+func (sp *searchPort) ClearMarked() {
+	sp.list.clearMarked()
+}
+
+// AddItems inserts streamed items into the list
+func (sp *searchPort) AddItems(items []dataproviders.Item) {
+	sp.list.addItems(items, sp.textInput.Value())
+}
+
 func (sp *searchPort) SetSize(width, height int) {
 	sp.width = width
 	sp.height = height
@@ -70,8 +175,12 @@ func (sp *searchPort) GetSelected() *dataproviders.Item {
 	return sp.list.getSelected()
 }
 
+func (sp *searchPort) listHeight() int {
+	return max(sp.height-inputHeight-helpHeight, 1)
+}
+
 func (sp *searchPort) View() string {
-	listHeight := max(sp.height-inputHeight-helpHeight, 1)
+	listHeight := sp.listHeight()
 
 	start, end := sp.list.calculateVisibleRange(listHeight)
 
@@ -91,7 +200,37 @@ func (sp *searchPort) View() string {
 	s.WriteString(strings.Join(renderedItems, ""))
 
 	// Render search input and help at the bottom.
-	s.WriteString("Search: " + sp.textInput.View() + "\n")
-	s.WriteString(sp.help.View(sp.keymap))
+	if sp.prompt != "" {
+		s.WriteString(sp.prompt + "\n")
+	} else {
+		s.WriteString("Search: " + sp.textInput.View() + "\n")
+	}
+	status := sp.statusView() + "  "
+	if sp.status != "" {
+		s.WriteString(status + sp.status)
+		return s.String()
+	}
+	s.WriteString(status + sp.helpView(sp.width-lipgloss.Width(status)))
 	return s.String()
 }
+
+// helpView shows how many items are marked in front of the help, truncated to
+// width to keep it on a single line
+func (sp *searchPort) helpView(width int) string {
+	marked := ""
+	if n := len(sp.list.marked); n > 0 {
+		marked = fmt.Sprintf("%d selected • ", n)
+	}
+	sp.help.Width = max(width-lipgloss.Width(marked), 0)
+	return marked + sp.help.View(sp.keymap)
+}
+
+// statusView shows how many items match the query out of all items, with a
+// spinner while items are still arriving
+func (sp *searchPort) statusView() string {
+	count := fmt.Sprintf("%d/%d", sp.list.matchCount(), sp.list.total)
+	if sp.loading {
+		return sp.spinner.View() + " " + count
+	}
+	return count
+}
@@ -0,0 +1,215 @@
+package fzf
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/niedch/mux-session/internal/tmux"
+)
+
+const (
+	actionKill   = "kill"
+	actionRename = "rename"
+	actionDetach = "detach"
+)
+
+// sessionPrompt asks for the input of an action on sessions before it runs:
+// the confirmation of a kill or the new name of a rename
+type sessionPrompt struct {
+	action   string
+	sessions []string
+	// kept is the session of the picker, which a kill leaves out
+	kept  string
+	input textinput.Model
+}
+
+func newSessionPrompt(action string, sessions []string) *sessionPrompt {
+	ti := textinput.New()
+	ti.CharLimit = 50
+	ti.Width = 20
+	if action == actionRename {
+		ti.SetValue(sessions[0])
+	}
+	ti.Focus()
+
+	return &sessionPrompt{action: action, sessions: sessions, input: ti}
+}
+
+func (p *sessionPrompt) View() string {
+	if p.action == actionKill {
+		if p.kept != "" {
+			return fmt.Sprintf("Kill %s, keeping %s of the picker? [y/N]", describeSessions(p.sessions), p.kept)
+		}
+		return fmt.Sprintf("Kill %s? [y/N]", describeSessions(p.sessions))
+	}
+	return fmt.Sprintf("Rename %s: %s", p.sessions[0], p.input.View())
+}
+
+// describeSessions names a single session, or counts several
+func describeSessions(sessions []string) string {
+	if len(sessions) == 1 {
+		return "session " + sessions[0]
+	}
+	return fmt.Sprintf("%d sessions", len(sessions))
+}
+
+// sessionActionMsg reports the result of an action on a session
+type sessionActionMsg struct {
+	message string
+	err     error
+}
+
+// refreshMsg carries the items reloaded after an action changed the sessions
+type refreshMsg struct {
+	items []dataproviders.Item
+	err   error
+}
+
+// runSessionAction runs the action in the background, message is shown once
+// it succeeded
+func runSessionAction(message string, action func() error) tea.Cmd {
+	return func() tea.Msg {
+		if err := action(); err != nil {
+			return sessionActionMsg{err: err}
+		}
+		return sessionActionMsg{message: message}
+	}
+}
+
+// refreshItems reloads all items from the data provider
+func refreshItems(provider dataproviders.DataProvider) tea.Cmd {
+	return func() tea.Msg {
+		items, err := provider.GetItems()
+		return refreshMsg{items: items, err: err}
+	}
+}
+
+// runningSessions returns the names of the sessions of the items which are
+// running, or an error if none of them is. sessionName names the session of an
+// item.
+func runningSessions(multiplexer tmux.Multiplexer, items []dataproviders.Item, sessionName func(item dataproviders.Item) string) ([]string, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no item selected")
+	}
+
+	sessions, err := multiplexer.ListSessions()
+	if err != nil && !errors.Is(err, tmux.ErrNoServer) {
+		return nil, err
+	}
+
+	var running []string
+	for _, item := range items {
+		if name := sessionName(item); slices.Contains(sessions, name) {
+			running = append(running, name)
+		}
+	}
+
+	if len(running) > 0 {
+		return running, nil
+	}
+	if len(items) == 1 {
+		return nil, fmt.Errorf("%s has no running session", items[0].Id)
+	}
+	return nil, fmt.Errorf("none of the selected items has a running session")
+}
+
+// nameSession returns the name of the session of the item, by its Id as tmux
+// stores it unless sessionName is set
+func (m model) nameSession(item dataproviders.Item) string {
+	if m.sessionName != nil {
+		return m.sessionName(item)
+	}
+	return tmux.SessionName(item.Id)
+}
+
+// startSessionAction handles the key of an action on the selected session. A
+// kill acts on all marked items instead if there are any.
+func (m model) startSessionAction(action string) (model, tea.Cmd) {
+	var items []dataproviders.Item
+	if selected := m.searchPort.GetSelected(); selected != nil {
+		items = append(items, *selected)
+	}
+	if marked := m.searchPort.MarkedItems(); action == actionKill && len(marked) > 0 {
+		items = marked
+	}
+
+	sessions, err := runningSessions(m.multiplexer, items, m.nameSession)
+	if err != nil {
+		m.searchPort.SetStatus(err.Error())
+		return m, nil
+	}
+
+	// Killing the session the picker runs in would kill the picker itself
+	kept := ""
+	if i := slices.Index(sessions, m.currentSession); action == actionKill && m.currentSession != "" && i >= 0 {
+		if len(sessions) == 1 {
+			m.searchPort.SetStatus(fmt.Sprintf("Cannot kill session %s, the picker runs in it", m.currentSession))
+			return m, nil
+		}
+		sessions = slices.Delete(sessions, i, i+1)
+		kept = m.currentSession
+	}
+
+	if action == actionDetach {
+		session := sessions[0]
+		return m, runSessionAction(fmt.Sprintf("Detached other clients from %s", session), func() error {
+			return m.multiplexer.DetachOtherClients(session)
+		})
+	}
+
+	m.prompt = newSessionPrompt(action, sessions)
+	m.prompt.kept = kept
+	m.searchPort.SetPrompt(m.prompt.View())
+	return m, nil
+}
+
+// updatePrompt handles a key while a prompt is shown. Escape cancels the
+// action, as does any key but y for a kill.
+func (m model) updatePrompt(msg tea.KeyMsg) (model, tea.Cmd) {
+	prompt := m.prompt
+
+	if msg.String() == "esc" || msg.String() == "ctrl+c" {
+		return m.closePrompt(), nil
+	}
+
+	if prompt.action == actionKill {
+		m = m.closePrompt()
+		if msg.String() != "y" && msg.String() != "Y" {
+			return m, nil
+		}
+		m.searchPort.ClearMarked()
+		return m, runSessionAction("Killed "+describeSessions(prompt.sessions), func() error {
+			var errs []error
+			for _, session := range prompt.sessions {
+				errs = append(errs, m.multiplexer.KillSession(session))
+			}
+			return errors.Join(errs...)
+		})
+	}
+
+	if msg.String() == "enter" {
+		m = m.closePrompt()
+		session, newName := prompt.sessions[0], prompt.input.Value()
+		if newName == "" || newName == session {
+			return m, nil
+		}
+		return m, runSessionAction(fmt.Sprintf("Renamed session %s to %s", session, newName), func() error {
+			return m.multiplexer.RenameSession(session, newName)
+		})
+	}
+
+	var cmd tea.Cmd
+	prompt.input, cmd = prompt.input.Update(msg)
+	m.searchPort.SetPrompt(prompt.View())
+	return m, cmd
+}
+
+func (m model) closePrompt() model {
+	m.prompt = nil
+	m.searchPort.SetPrompt("")
+	return m
+}
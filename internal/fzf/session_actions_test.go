@@ -0,0 +1,342 @@
+package fzf
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/niedch/mux-session/internal/tmux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticProvider struct {
+	items []dataproviders.Item
+}
+
+func (p *staticProvider) GetItems() ([]dataproviders.Item, error) {
+	return p.items, nil
+}
+
+type emptyPreview struct{}
+
+func (emptyPreview) Render(item any) (string, error) { return "", nil }
+func (emptyPreview) Name() string                    { return "empty" }
+func (emptyPreview) SetWidth(width int) error        { return nil }
+
+// newActionModel lists the sessions of recorder and the directories like the
+// picker does, the last item is selected
+func newActionModel(t *testing.T, recorder *tmux.Recorder, directories ...string) model {
+	t.Helper()
+
+	var items []dataproviders.Item
+	for _, dir := range directories {
+		items = append(items, dataproviders.Item{Id: dir, Display: dir, Path: "/src/" + dir})
+	}
+	provider := dataproviders.NewDeduplicatorProvider(&staticProvider{items: items}, dataproviders.NewTmuxProvider(recorder))
+
+	all, err := provider.GetItems()
+	require.NoError(t, err)
+
+	sp := newSearchPort(all, func([]dataproviders.Item) {}, DisplayField, 80, 20)
+	return model{
+		searchPort:   sp,
+		previewPort:  newPreviewPort(emptyPreview{}, 80, 20),
+		dataProvider: provider,
+		multiplexer:  recorder,
+	}
+}
+
+// send updates the model with the message and every action and refresh
+// message its commands produce
+func send(m model, msg tea.Msg) model {
+	updated, cmd := m.Update(msg)
+	m = updated.(model)
+
+	for _, msg := range collect(cmd) {
+		switch msg.(type) {
+		case sessionActionMsg, refreshMsg:
+			m = send(m, msg)
+		}
+	}
+	return m
+}
+
+// collect runs the command and returns its messages. Commands which wait,
+// like the blinking of the cursor, are given up on.
+func collect(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+
+	result := make(chan tea.Msg, 1)
+	go func() { result <- cmd() }()
+
+	var msg tea.Msg
+	select {
+	case msg = <-result:
+	case <-time.After(50 * time.Millisecond):
+		return nil
+	}
+
+	batch, ok := msg.(tea.BatchMsg)
+	if !ok {
+		return []tea.Msg{msg}
+	}
+
+	var msgs []tea.Msg
+	for _, cmd := range batch {
+		msgs = append(msgs, collect(cmd)...)
+	}
+	return msgs
+}
+
+func typeText(m model, text string) model {
+	return send(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+}
+
+func listedIds(m model) []string {
+	return ids(m.searchPort.list.items)
+}
+
+func newRecorderWith(t *testing.T, sessions ...string) *tmux.Recorder {
+	t.Helper()
+
+	recorder := tmux.NewRecorder()
+	for _, session := range sessions {
+		require.NoError(t, recorder.NewSession(session, "main", "/src/"+session, nil))
+	}
+	return recorder
+}
+
+func TestSessionActions_Kill(t *testing.T) {
+	tests := []struct {
+		name     string
+		answer   string
+		sessions []string
+		listed   []string
+		status   string
+	}{
+		{
+			name:     "confirmed",
+			answer:   "y",
+			sessions: []string{"api"},
+			listed:   []string{"api"},
+			status:   "Killed session scratch",
+		},
+		{
+			name:     "cancelled",
+			answer:   "n",
+			sessions: []string{"api", "scratch"},
+			listed:   []string{"api", "scratch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := newRecorderWith(t, "api", "scratch")
+			m := newActionModel(t, recorder)
+
+			m = send(m, tea.KeyMsg{Type: tea.KeyCtrlX})
+			require.NotNil(t, m.prompt)
+			assert.Contains(t, m.searchPort.View(), "Kill session scratch? [y/N]")
+
+			m = typeText(m, tt.answer)
+			assert.Nil(t, m.prompt)
+
+			sessions, err := recorder.ListSessions()
+			require.NoError(t, err)
+			assert.Equal(t, tt.sessions, sessions)
+			assert.Equal(t, tt.listed, listedIds(m))
+			assert.Equal(t, tt.status, m.searchPort.status)
+		})
+	}
+}
+
+func TestSessionActions_KillMarked(t *testing.T) {
+	recorder := newRecorderWith(t, "api", "scratch", "web")
+	m := newActionModel(t, recorder, "docs")
+
+	// Marks docs, web, scratch and api, then unmarks api again. docs has no
+	// session to kill.
+	for range 4 {
+		m = send(m, tea.KeyMsg{Type: tea.KeyTab})
+	}
+	m = send(m, tea.KeyMsg{Type: tea.KeyShiftTab})
+	view := m.searchPort.View()
+	helpLine := view[strings.LastIndex(view, "\n")+1:]
+	assert.Contains(t, helpLine, "3 selected • ", "the count leads the help line")
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyCtrlX})
+	require.NotNil(t, m.prompt)
+	assert.Contains(t, m.searchPort.View(), "Kill 2 sessions? [y/N]")
+
+	m = typeText(m, "y")
+
+	assert.NotNil(t, recorder.Session("api"))
+	assert.Nil(t, recorder.Session("scratch"))
+	assert.Nil(t, recorder.Session("web"))
+	assert.Equal(t, []string{"api", "docs"}, listedIds(m))
+	assert.Equal(t, "Killed 2 sessions", m.searchPort.status)
+	assert.Empty(t, m.searchPort.MarkedItems())
+}
+
+func TestSessionActions_KillKeepsCurrentSession(t *testing.T) {
+	t.Run("selected", func(t *testing.T) {
+		recorder := newRecorderWith(t, "api", "scratch")
+		m := newActionModel(t, recorder)
+		m.currentSession = "scratch"
+
+		m = send(m, tea.KeyMsg{Type: tea.KeyCtrlX})
+
+		assert.Nil(t, m.prompt)
+		assert.Equal(t, "Cannot kill session scratch, the picker runs in it", m.searchPort.status)
+		assert.NotNil(t, recorder.Session("scratch"))
+	})
+
+	t.Run("marked", func(t *testing.T) {
+		recorder := newRecorderWith(t, "api", "scratch", "web")
+		m := newActionModel(t, recorder)
+		m.currentSession = "scratch"
+		for range 3 {
+			m = send(m, tea.KeyMsg{Type: tea.KeyTab})
+		}
+
+		m = send(m, tea.KeyMsg{Type: tea.KeyCtrlX})
+		require.NotNil(t, m.prompt)
+		assert.Contains(t, m.searchPort.View(), "Kill 2 sessions, keeping scratch of the picker? [y/N]")
+
+		m = typeText(m, "y")
+
+		assert.Nil(t, recorder.Session("api"))
+		assert.NotNil(t, recorder.Session("scratch"))
+		assert.Nil(t, recorder.Session("web"))
+		assert.Equal(t, "Killed 2 sessions", m.searchPort.status)
+	})
+}
+
+func TestSessionActions_Rename(t *testing.T) {
+	recorder := newRecorderWith(t, "scratch")
+	m := newActionModel(t, recorder, "api")
+	m.searchPort.list.moveUp()
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyCtrlR})
+	require.NotNil(t, m.prompt)
+	assert.Equal(t, "scratch", m.prompt.input.Value())
+
+	for range "scratch" {
+		m = send(m, tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	m = typeText(m, "notes")
+	m = send(m, tea.KeyMsg{Type: tea.KeyEnter})
+
+	assert.Nil(t, m.prompt)
+	assert.NotNil(t, recorder.Session("notes"))
+	assert.Nil(t, recorder.Session("scratch"))
+	assert.Equal(t, []string{"notes", "api"}, listedIds(m))
+	assert.Equal(t, "Renamed session scratch to notes", m.searchPort.status)
+	assert.Empty(t, m.searchPort.textInput.Value(), "the new name must not go into the search")
+}
+
+func TestSessionActions_RenameCancelled(t *testing.T) {
+	recorder := newRecorderWith(t, "scratch")
+	m := newActionModel(t, recorder)
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyCtrlR})
+	m = typeText(m, "-old")
+	m = send(m, tea.KeyMsg{Type: tea.KeyEsc})
+
+	assert.Nil(t, m.prompt)
+	assert.NotNil(t, recorder.Session("scratch"))
+	assert.Contains(t, m.searchPort.View(), "Search: ")
+	assert.NotContains(t, m.searchPort.View(), "Rename")
+}
+
+func TestSessionActions_DetachOthers(t *testing.T) {
+	recorder := newRecorderWith(t, "scratch")
+	m := newActionModel(t, recorder)
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyCtrlT})
+
+	assert.Equal(t, []string{"scratch"}, recorder.Detached)
+	assert.Equal(t, "Detached other clients from scratch", m.searchPort.status)
+}
+
+func TestSessionActions_NoSession(t *testing.T) {
+	recorder := newRecorderWith(t)
+	m := newActionModel(t, recorder, "web")
+
+	m = send(m, tea.KeyMsg{Type: tea.KeyCtrlX})
+
+	assert.Nil(t, m.prompt)
+	assert.Equal(t, "web has no running session", m.searchPort.status)
+}
+
+func TestRunningSessions(t *testing.T) {
+	recorder := newRecorderWith(t, "org/api_v2", "work")
+	items := []dataproviders.Item{{Id: "org/api.v2"}, {Id: "web"}, {Id: "docs"}}
+
+	sessions, err := runningSessions(recorder, items, model{}.nameSession)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"org/api_v2"}, sessions, "a dotted id should find the session tmux named")
+
+	configured := model{sessionName: func(item dataproviders.Item) string {
+		if item.Id == "web" {
+			return "work"
+		}
+		return item.Id
+	}}
+	sessions, err = runningSessions(recorder, items, configured.nameSession)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"work"}, sessions, "the configured session name should be looked up")
+
+	_, err = runningSessions(recorder, items[2:], model{}.nameSession)
+	assert.EqualError(t, err, "docs has no running session")
+}
+
+func TestPick(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      tea.KeyMsg
+		marks    int
+		expected *Result
+	}{
+		{
+			name:     "enter opens the selected item",
+			key:      tea.KeyMsg{Type: tea.KeyEnter},
+			expected: &Result{Action: ResultOpen, Items: []dataproviders.Item{{Id: "web", Display: "web", Path: "/src/web"}}},
+		},
+		{
+			name:  "enter opens the marked items",
+			key:   tea.KeyMsg{Type: tea.KeyEnter},
+			marks: 2,
+			expected: &Result{Action: ResultOpen, Items: []dataproviders.Item{
+				{Id: "web", Display: "web", Path: "/src/web"},
+				{Id: "api", Display: "api", Path: "/src/api"},
+			}},
+		},
+		{
+			name:  "ctrl+y prints the marked items",
+			key:   tea.KeyMsg{Type: tea.KeyCtrlY},
+			marks: 1,
+			expected: &Result{Action: ResultPrint, Items: []dataproviders.Item{
+				{Id: "web", Display: "web", Path: "/src/web"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newActionModel(t, newRecorderWith(t), "api", "web")
+			for range tt.marks {
+				m = send(m, tea.KeyMsg{Type: tea.KeyTab})
+			}
+
+			m = send(m, tt.key)
+
+			assert.Equal(t, tt.expected, m.result)
+		})
+	}
+}
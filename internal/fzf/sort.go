@@ -0,0 +1,77 @@
+package fzf
+
+import (
+	"cmp"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/niedch/mux-session/internal/conf"
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/niedch/mux-session/internal/history"
+)
+
+// Sorter orders the unfiltered items in place, from the top of the list to
+// the bottom, where the cursor starts. The order also breaks ties between
+// equally good matches of a query.
+type Sorter func(items []dataproviders.Item)
+
+// NewSorter returns the Sorter for the sort setting of the config
+func NewSorter(config *conf.Config) (Sorter, error) {
+	switch config.Sort {
+	case conf.SortAlpha:
+		return sortAlpha, nil
+	case conf.SortMtime:
+		return newMtimeSorter(), nil
+	default:
+		store, err := history.DefaultStore()
+		if err != nil {
+			return nil, err
+		}
+		entries, err := store.Entries()
+		if err != nil {
+			return nil, err
+		}
+		return newFrecencySorter(history.Frecency(entries, time.Now())), nil
+	}
+}
+
+// sortAlpha lists the items by name
+func sortAlpha(items []dataproviders.Item) {
+	slices.SortStableFunc(items, func(a, b dataproviders.Item) int {
+		return strings.Compare(strings.ToLower(a.Id), strings.ToLower(b.Id))
+	})
+}
+
+// newMtimeSorter puts the most recently modified directories at the bottom
+func newMtimeSorter() Sorter {
+	mtimes := make(map[string]int64)
+	mtime := func(path string) int64 {
+		if t, ok := mtimes[path]; ok {
+			return t
+		}
+		var t int64
+		if info, err := os.Stat(path); err == nil {
+			t = info.ModTime().UnixNano()
+		}
+		mtimes[path] = t
+		return t
+	}
+
+	return func(items []dataproviders.Item) {
+		slices.SortStableFunc(items, func(a, b dataproviders.Item) int {
+			return cmp.Compare(mtime(a.Path), mtime(b.Path))
+		})
+	}
+}
+
+// newFrecencySorter puts the most frecent items at the bottom. Items never
+// opened keep their order above them.
+func newFrecencySorter(scores map[string]float64) Sorter {
+	return func(items []dataproviders.Item) {
+		slices.SortStableFunc(items, func(a, b dataproviders.Item) int {
+			return cmp.Compare(scores[a.Id], scores[b.Id])
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package fzf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func itemIds(items []dataproviders.Item) []string {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.Id)
+	}
+	return ids
+}
+
+func TestSorters(t *testing.T) {
+	dir := t.TempDir()
+	for i, name := range []string{"newest", "Beta", "alpha"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.Mkdir(path, 0o755))
+		mtime := time.Unix(1700000000, 0).Add(-time.Duration(i) * time.Hour)
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	items := func() []dataproviders.Item {
+		return []dataproviders.Item{
+			{Id: "newest", Path: filepath.Join(dir, "newest")},
+			{Id: "Beta", Path: filepath.Join(dir, "Beta")},
+			{Id: "alpha", Path: filepath.Join(dir, "alpha")},
+			{Id: "session", Path: "session"},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		sorter   Sorter
+		expected []string
+	}{
+		{
+			name:     "alpha ignores case",
+			sorter:   sortAlpha,
+			expected: []string{"alpha", "Beta", "newest", "session"},
+		},
+		{
+			name:     "mtime puts the most recent directory at the bottom",
+			sorter:   newMtimeSorter(),
+			expected: []string{"session", "alpha", "Beta", "newest"},
+		},
+		{
+			name:     "frecency puts the most frecent item at the bottom",
+			sorter:   newFrecencySorter(map[string]float64{"alpha": 4, "Beta": 0.5}),
+			expected: []string{"newest", "session", "Beta", "alpha"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted := items()
+			tt.sorter(sorted)
+			assert.Equal(t, tt.expected, itemIds(sorted))
+		})
+	}
+}
+
+func TestList_SortsStreamedItems(t *testing.T) {
+	l := newList(nil).withSorter(newFrecencySorter(map[string]float64{"api": 2}))
+
+	l.addItems([]dataproviders.Item{{Id: "api", Display: "api"}, {Id: "web", Display: "web"}}, "")
+	l.addItems([]dataproviders.Item{{Id: "docs", Display: "docs"}}, "")
+
+	assert.Equal(t, []string{"web", "docs", "api"}, itemIds(l.items))
+	assert.Equal(t, "api", l.getSelected().Id, "the most frecent item is selected")
+
+	l.addItems([]dataproviders.Item{{Id: "web", Display: "web (changed)"}}, "")
+	assert.Equal(t, []string{"web", "docs", "api"}, itemIds(l.items))
+	assert.Equal(t, "web (changed)", l.items[0].Display)
+}
+
+func TestFilterTree_FrecencyBreaksTies(t *testing.T) {
+	items := []dataproviders.Item{{Id: "api-a", Display: "api-a"}, {Id: "api-b", Display: "api-b"}}
+	newFrecencySorter(map[string]float64{"api-a": 1})(items)
+
+	assert.Equal(t, []string{"api-b", "api-a"}, itemIds(FilterTree(items, "api", DisplayField)))
+}
@@ -0,0 +1,27 @@
+package history
+
+import "time"
+
+// Frecency scores every item Id in the entries by how often and how recently
+// it was opened. Like zoxide, each visit counts more the more recent it is.
+func Frecency(entries []Entry, now time.Time) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, entry := range entries {
+		scores[entry.Id] += recencyWeight(now.Sub(entry.Time))
+	}
+
+	return scores
+}
+
+func recencyWeight(age time.Duration) float64 {
+	switch {
+	case age < time.Hour:
+		return 4
+	case age < 24*time.Hour:
+		return 2
+	case age < 7*24*time.Hour:
+		return 0.5
+	default:
+		return 0.25
+	}
+}
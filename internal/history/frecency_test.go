@@ -0,0 +1,32 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrecency(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	visit := func(id string, ago time.Duration) Entry {
+		return Entry{Time: now.Add(-ago), Action: ActionSwitch, Id: id}
+	}
+
+	entries := []Entry{
+		visit("recent", 10*time.Minute),
+		visit("today", 5*time.Hour),
+		visit("frequent", 30*24*time.Hour),
+		visit("frequent", 40*24*time.Hour),
+		visit("frequent", 50*24*time.Hour),
+		visit("week", 3*24*time.Hour),
+	}
+
+	scores := Frecency(entries, now)
+
+	assert.Equal(t, 4.0, scores["recent"])
+	assert.Equal(t, 2.0, scores["today"])
+	assert.Equal(t, 0.75, scores["frequent"])
+	assert.Equal(t, 0.5, scores["week"])
+	assert.Zero(t, scores["never"])
+}
@@ -0,0 +1,171 @@
+package history
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+const (
+	ActionCreate = "create"
+	ActionSwitch = "switch"
+)
+
+// maxEntries bounds the size of the history. Once it is exceeded the oldest
+// entries are dropped, they hardly count for the frecency anyway.
+const maxEntries = 5000
+
+// Entry is a session which was created or switched to, with the item it was
+// opened from
+type Entry struct {
+	Time     time.Time
+	Action   string
+	Id       string
+	Path     string
+	ParentId string
+	// ParentPath is the path of the repository of a worktree
+	ParentPath string
+}
+
+// Store is the history of sessions opened by mux-session
+type Store struct {
+	path string
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// DefaultStore keeps the history in XDG_DATA_HOME/mux-session/history
+func DefaultStore() (*Store, error) {
+	path, err := xdg.DataFile(filepath.Join("mux-session", "history"))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStore(path), nil
+}
+
+// Record appends the entry to the history
+func (s *Store) Record(entry Entry) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteString(formatEntry(entry)); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return s.compact()
+}
+
+// Entries returns the history, oldest entry first
+func (s *Store) Entries() ([]Entry, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry, err := parseEntry(scanner.Text())
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// compact drops the oldest entries once the history exceeds maxEntries
+func (s *Store) compact() error {
+	entries, err := s.Entries()
+	if err != nil || len(entries) <= maxEntries {
+		return err
+	}
+
+	var content strings.Builder
+	for _, entry := range entries[len(entries)-maxEntries:] {
+		content.WriteString(formatEntry(entry))
+	}
+
+	return os.WriteFile(s.path, []byte(content.String()), 0o600)
+}
+
+// formatEntry writes an entry as one
+// "<unix time>\t<action>\t<id>\t<path>\t<parent id>\t<parent path>" line
+func formatEntry(entry Entry) string {
+	return fmt.Sprintf("%d\t%s\t%s\t%s\t%s\t%s\n", entry.Time.Unix(), entry.Action, entry.Id, entry.Path, entry.ParentId, entry.ParentPath)
+}
+
+// parseEntry reads a line written by formatEntry. The parent id and path are
+// optional, older entries do not have them.
+func parseEntry(line string) (Entry, error) {
+	fields := strings.Split(line, "\t")
+	for len(fields) >= 4 && len(fields) < 6 {
+		fields = append(fields, "")
+	}
+	if len(fields) != 6 {
+		return Entry{}, fmt.Errorf("invalid history entry %q", line)
+	}
+
+	seconds, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return Entry{}, fmt.Errorf("invalid history entry %q: %w", line, err)
+	}
+
+	return Entry{
+		Time:       time.Unix(seconds, 0),
+		Action:     fields[1],
+		Id:         fields[2],
+		Path:       fields[3],
+		ParentId:   fields[4],
+		ParentPath: fields[5],
+	}, nil
+}
+
+// Previous returns the most recent entry of another session than current, the
+// session of the calling client, so that opening it flips between the last two
+// sessions. Without a current session, e.g. outside of tmux, the most recent
+// entry is taken as the current one. Entries whose path is no longer a
+// directory are skipped, like those of sessions without a directory.
+func Previous(entries []Entry, current string) (Entry, bool) {
+	if current == "" && len(entries) > 0 {
+		current = entries[len(entries)-1].Id
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Id != current && isDir(entries[i].Path) {
+			return entries[i], true
+		}
+	}
+
+	return Entry{}, false
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,108 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_RecordAndEntries(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "mux-session", "history"))
+
+	entries, err := store.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries, "a missing history is empty")
+
+	now := time.Unix(1700000000, 0)
+	require.NoError(t, store.Record(Entry{Time: now, Action: ActionCreate, Id: "api", Path: "/src/api"}))
+	require.NoError(t, store.Record(Entry{Time: now.Add(time.Minute), Action: ActionSwitch, Id: "web", Path: "/src/my web"}))
+	require.NoError(t, store.Record(Entry{Time: now.Add(2 * time.Minute), Action: ActionCreate, Id: "fix", Path: "/wt/fix", ParentId: "api", ParentPath: "/src/api"}))
+
+	entries, err = store.Entries()
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Time: now, Action: ActionCreate, Id: "api", Path: "/src/api"},
+		{Time: now.Add(time.Minute), Action: ActionSwitch, Id: "web", Path: "/src/my web"},
+		{Time: now.Add(2 * time.Minute), Action: ActionCreate, Id: "fix", Path: "/wt/fix", ParentId: "api", ParentPath: "/src/api"},
+	}, entries)
+}
+
+func TestStore_SkipsInvalidLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	require.NoError(t, os.WriteFile(path, []byte("garbage\n1700000000\tswitch\tapi\t/src/api\nnope\tswitch\tweb\t/src/web\n"), 0o600))
+
+	entries, err := NewStore(path).Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "api", entries[0].Id)
+}
+
+func TestStore_DropsOldestEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	var content strings.Builder
+	for i := range maxEntries {
+		fmt.Fprintf(&content, "%d\tswitch\tproject-%d\t/src/project-%d\n", 1700000000+i, i, i)
+	}
+	require.NoError(t, os.WriteFile(path, []byte(content.String()), 0o600))
+
+	store := NewStore(path)
+	require.NoError(t, store.Record(Entry{Time: time.Unix(1800000000, 0), Action: ActionCreate, Id: "newest", Path: "/src/newest"}))
+
+	entries, err := store.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, maxEntries)
+	assert.Equal(t, "project-1", entries[0].Id)
+	assert.Equal(t, "newest", entries[len(entries)-1].Id)
+}
+
+func TestPrevious(t *testing.T) {
+	root := t.TempDir()
+	entry := func(id string) Entry {
+		path := filepath.Join(root, id)
+		require.NoError(t, os.MkdirAll(path, 0o755))
+		return Entry{Action: ActionSwitch, Id: id, Path: path}
+	}
+
+	tests := []struct {
+		name     string
+		entries  []Entry
+		current  string
+		expected string
+	}{
+		{name: "empty history", entries: nil},
+		{name: "only one session", entries: []Entry{entry("api"), entry("api")}},
+		{name: "flips between the last two", entries: []Entry{entry("web"), entry("api"), entry("docs")}, expected: "api"},
+		{name: "repeated opens of the current session are skipped", entries: []Entry{entry("web"), entry("api"), entry("api")}, expected: "web"},
+		{name: "current session switched to without mux-session", entries: []Entry{entry("web"), entry("api")}, current: "scratch", expected: "api"},
+		{name: "current session is not the newest entry", entries: []Entry{entry("web"), entry("api"), entry("docs")}, current: "api", expected: "docs"},
+		{name: "sessions without a directory are skipped", entries: []Entry{entry("web"), {Action: ActionSwitch, Id: "scratch", Path: "scratch"}, entry("api")}, expected: "web"},
+		{name: "removed directories are skipped", entries: []Entry{entry("web"), {Action: ActionSwitch, Id: "old", Path: filepath.Join(root, "old")}, entry("api")}, expected: "web"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous, ok := Previous(tt.entries, tt.current)
+			assert.Equal(t, tt.expected != "", ok)
+			assert.Equal(t, tt.expected, previous.Id)
+		})
+	}
+}
+
+func TestStore_ReadsEntriesWithoutParent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	require.NoError(t, os.WriteFile(path, []byte("1700000000\tswitch\tapi\t/src/api\n1700000001\tcreate\tfeature\t/src/feature\tapi\n"), 0o600))
+
+	entries, err := NewStore(path).Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Empty(t, entries[0].ParentId)
+	assert.Equal(t, "api", entries[1].ParentId)
+	assert.Empty(t, entries[1].ParentPath)
+}
@@ -0,0 +1,61 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	OnCreate = "on_create"
+	OnSwitch = "on_switch"
+	OnDetach = "on_detach"
+)
+
+// Hook is a shell command run on a session lifecycle event
+type Hook struct {
+	Event   string
+	Command string
+	Session string
+	Path    string
+}
+
+// Env returns the variables describing the session to the hook
+func (h Hook) Env() map[string]string {
+	return map[string]string{
+		"MUX_SESSION_NAME": h.Session,
+		"MUX_SESSION_PATH": h.Path,
+	}
+}
+
+// Run executes the hook with sh in the project path and waits for it
+func Run(h Hook) error {
+	cmd := exec.Command("sh", "-c", h.Command)
+	cmd.Dir = h.Path
+	cmd.Env = os.Environ()
+	for k, v := range h.Env() {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(output))
+		if msg == "" {
+			return fmt.Errorf("%s hook failed: %w", h.Event, err)
+		}
+		return fmt.Errorf("%s hook failed: %w: %s", h.Event, err, msg)
+	}
+	return nil
+}
+
+// Script returns the hook as a single sh command line, for hooks which are
+// run by tmux itself rather than by mux-session
+func (h Hook) Script() string {
+	return fmt.Sprintf("cd %s && MUX_SESSION_NAME=%s MUX_SESSION_PATH=%s sh -c %s",
+		shellQuote(h.Path), shellQuote(h.Session), shellQuote(h.Path), shellQuote(h.Command))
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,54 @@
+package hooks
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	hook := Hook{
+		Event:   OnCreate,
+		Command: `echo "$MUX_SESSION_NAME $MUX_SESSION_PATH" > status`,
+		Session: "my-project",
+		Path:    dir,
+	}
+
+	require.NoError(t, Run(hook))
+
+	content, err := os.ReadFile(filepath.Join(dir, "status"))
+	require.NoError(t, err)
+	assert.Equal(t, "my-project "+dir+"\n", string(content))
+}
+
+func TestRun_Failure(t *testing.T) {
+	hook := Hook{Event: OnSwitch, Command: "echo broken >&2; exit 3", Path: t.TempDir()}
+
+	err := Run(hook)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "on_switch hook failed")
+	assert.Contains(t, err.Error(), "broken")
+}
+
+func TestScript(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "it's here")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	hook := Hook{
+		Event:   OnDetach,
+		Command: `printf '%s' "$MUX_SESSION_NAME" > status`,
+		Session: "my-project",
+		Path:    dir,
+	}
+
+	require.NoError(t, exec.Command("sh", "-c", hook.Script()).Run())
+
+	content, err := os.ReadFile(filepath.Join(dir, "status"))
+	require.NoError(t, err)
+	assert.Equal(t, "my-project", string(content))
+}
@@ -1,6 +1,7 @@
 package orchestrator
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"slices"
@@ -9,48 +10,145 @@ import (
 
 	"github.com/niedch/mux-session/internal/conf"
 	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/niedch/mux-session/internal/hooks"
 	"github.com/niedch/mux-session/internal/tmux"
 )
 
 type OrchestratorService struct {
-	tmux *tmux.Tmux
+	tmux              tmux.Multiplexer
+	keepPartial       bool
+	reconcileOnSwitch bool
+	runHook           func(hooks.Hook) error
 }
 
-func New(tmux *tmux.Tmux) *OrchestratorService {
+func New(tmux tmux.Multiplexer) *OrchestratorService {
 	return &OrchestratorService{
-		tmux: tmux,
+		tmux:    tmux,
+		runHook: hooks.Run,
 	}
 }
 
-func (m *OrchestratorService) CreateSession(item *dataproviders.Item, projectConfig conf.ProjectConfig) error {
-	dirPath := item.Path
-	sessionName := filepath.Base(dirPath)
+// WithHookRunner replaces how on_create and on_switch hooks are executed
+func (m *OrchestratorService) WithHookRunner(runHook func(hooks.Hook) error) *OrchestratorService {
+	m.runHook = runHook
+	return m
+}
+
+// WithKeepPartial keeps a partially created session around for debugging
+// instead of killing it when CreateSession fails
+func (m *OrchestratorService) WithKeepPartial(keep bool) *OrchestratorService {
+	m.keepPartial = keep
+	return m
+}
+
+// WithReconcileOnSwitch creates missing windows of an existing session before
+// switching to it
+func (m *OrchestratorService) WithReconcileOnSwitch(reconcile bool) *OrchestratorService {
+	m.reconcileOnSwitch = reconcile
+	return m
+}
 
+// ReconcileResult lists the windows changed by ReconcileSession
+type ReconcileResult struct {
+	// Created are windows from the project config missing in the session
+	Created []string
+	// Extra are windows of the session not in the project config. They are
+	// reported only and never killed.
+	Extra []string
+}
+
+// SessionName returns the name of the session of the item as tmux knows it,
+// the name of its project config or else its Id
+func SessionName(item *dataproviders.Item, projectConfig conf.ProjectConfig) string {
+	name := item.Id
 	if projectConfig.Name != nil {
-		sessionName = *projectConfig.Name
+		name = *projectConfig.Name
+	}
+	return tmux.SessionName(name)
+}
+
+func (m *OrchestratorService) CreateSession(item *dataproviders.Item, projectConfig conf.ProjectConfig) error {
+	sessionName, err := m.createSession(item, projectConfig)
+	if err != nil {
+		return err
+	}
+
+	// Switch to the new session
+	return m.tmux.SwitchSession(sessionName)
+}
+
+// EnsureSession creates the session of the item without switching to it,
+// unless it is running already. It reports whether the session was created.
+func (m *OrchestratorService) EnsureSession(item *dataproviders.Item, projectConfig conf.ProjectConfig) (bool, error) {
+	sessions, err := m.tmux.ListSessions()
+	if err != nil && !errors.Is(err, tmux.ErrNoServer) {
+		return false, err
+	}
+	if slices.Contains(sessions, SessionName(item, projectConfig)) {
+		return false, nil
+	}
+
+	if _, err := m.createSession(item, projectConfig); err != nil {
+		return false, err
 	}
+	return true, nil
+}
+
+// createSession creates the session of the item in the background and returns
+// its name. A session of that name created in the meantime is kept as it is.
+func (m *OrchestratorService) createSession(item *dataproviders.Item, projectConfig conf.ProjectConfig) (string, error) {
+	dirPath := item.Path
+	sessionName := SessionName(item, projectConfig)
 
 	if len(projectConfig.WindowConfig) == 0 {
-		return fmt.Errorf("no window configuration found for session %s", sessionName)
+		return "", fmt.Errorf("no window configuration found for session %s", sessionName)
 	}
 
 	firstWindow := projectConfig.WindowConfig[0]
 
 	logger.Printf("Creating Session %s\n", sessionName)
 	if err := m.tmux.NewSession(sessionName, firstWindow.WindowName, dirPath, projectConfig.Env); err != nil {
-		return fmt.Errorf("Failed to create Session %s", sessionName)
+		// The session was created in the meantime, use it instead
+		if errors.Is(err, tmux.ErrDuplicateSession) {
+			logger.Printf("Session %s already exists, using it\n", sessionName)
+			return sessionName, nil
+		}
+		return "", fmt.Errorf("Failed to create Session %s: %w", sessionName, err)
 	}
 
+	data := conf.NewTemplateData(item, sessionName, projectConfig.Env)
+	if err := m.buildSession(sessionName, dirPath, projectConfig, data); err != nil {
+		if m.keepPartial {
+			logger.Printf("Keeping partially created session %s\n", sessionName)
+			return "", fmt.Errorf("session %s was left partially created: %w", sessionName, err)
+		}
+
+		logger.Printf("Rolling back session %s\n", sessionName)
+		if killErr := m.tmux.KillSession(sessionName); killErr != nil {
+			return "", fmt.Errorf("failed to roll back session %s after error: %w (rollback: %v)", sessionName, err, killErr)
+		}
+		return "", fmt.Errorf("session %s was rolled back: %w", sessionName, err)
+	}
+
+	m.fireHook(hooks.OnCreate, projectConfig.OnCreate, sessionName, dirPath)
+
+	return sessionName, nil
+}
+
+// buildSession creates the windows and panels of a freshly created session
+func (m *OrchestratorService) buildSession(sessionName string, dirPath string, projectConfig conf.ProjectConfig, data conf.TemplateData) error {
+	firstWindow := projectConfig.WindowConfig[0]
+
 	// Setup panels for first window if configured
 	if len(firstWindow.PanelConfig) > 0 {
-		if err := m.setupPanels(sessionName, firstWindow.WindowName, dirPath, firstWindow.PanelConfig); err != nil {
-			return fmt.Errorf("failed to setup panels for first window: %w", err)
+		if err := m.setupPanels(sessionName, firstWindow.WindowName, dirPath, firstWindow.PanelConfig, data); err != nil {
+			return fmt.Errorf("failed to setup panels for window %s: %w", firstWindow.WindowName, err)
 		}
 	}
 
 	// Create additional windows
 	for _, window := range projectConfig.WindowConfig[1:] {
-		if err := m.createWindowWithPanels(sessionName, dirPath, window, projectConfig.Env); err != nil {
+		if err := m.createWindowWithPanels(sessionName, dirPath, window, projectConfig.Env, data); err != nil {
 			return err
 		}
 	}
@@ -58,11 +156,18 @@ func (m *OrchestratorService) CreateSession(item *dataproviders.Item, projectCon
 	// Execute window command if specified (after panels are created)
 	if firstWindow.Cmd != nil && *firstWindow.Cmd != "" {
 		target := fmt.Sprintf("%s:%s", sessionName, firstWindow.WindowName)
-		if err := m.tmux.SendKeys(target, *firstWindow.Cmd); err != nil {
+		if err := m.sendCommand(target, *firstWindow.Cmd, data); err != nil {
 			return fmt.Errorf("failed to send command to window %s: %w", firstWindow.WindowName, err)
 		}
 	}
 
+	if projectConfig.OnDetach != "" {
+		hook := hooks.Hook{Event: hooks.OnDetach, Command: projectConfig.OnDetach, Session: sessionName, Path: dirPath}
+		if err := m.tmux.SetHook(sessionName, "client-detached", hook.Script()); err != nil {
+			return fmt.Errorf("failed to register on_detach hook: %w", err)
+		}
+	}
+
 	// Select primary window if configured, otherwise select first window
 	primaryWindow := m.findPrimaryWindow(projectConfig.WindowConfig)
 	if primaryWindow != "" {
@@ -73,18 +178,40 @@ func (m *OrchestratorService) CreateSession(item *dataproviders.Item, projectCon
 		}
 	}
 
-	// Switch to the new session
-	return m.tmux.SwitchSession(sessionName)
+	return nil
 }
 
-func (m *OrchestratorService) SwitchSession(selected *dataproviders.Item) (bool, error) {
+func (m *OrchestratorService) SwitchSession(selected *dataproviders.Item, projectConfig conf.ProjectConfig) (bool, error) {
 	sessions, err := m.tmux.ListSessions()
 	if err != nil {
+		// Without a server there is no session to switch to yet
+		if errors.Is(err, tmux.ErrNoServer) {
+			return false, nil
+		}
 		return false, err
 	}
 
-	if slices.Contains(sessions, selected.Id) {
-		if err := m.tmux.SwitchSession(selected.Id); err != nil {
+	sessionName := SessionName(selected, projectConfig)
+	if slices.Contains(sessions, sessionName) {
+		if m.reconcileOnSwitch {
+			m.reconcile(selected, projectConfig)
+		}
+
+		// Outside of tmux switching attaches and blocks until the client
+		// detaches, so the hook runs first
+		if projectConfig.OnSwitch != "" {
+			if dirPath, err := m.projectDir(selected, sessionName); err != nil {
+				logger.Printf("Skipping on_switch hook of session %s: %v\n", sessionName, err)
+			} else {
+				m.fireHook(hooks.OnSwitch, projectConfig.OnSwitch, sessionName, dirPath)
+			}
+		}
+
+		if err := m.tmux.SwitchSession(sessionName); err != nil {
+			// The session was killed since listing it, so it has to be created
+			if errors.Is(err, tmux.ErrSessionNotFound) {
+				return false, nil
+			}
 			return false, err
 		}
 
@@ -94,7 +221,108 @@ func (m *OrchestratorService) SwitchSession(selected *dataproviders.Item) (bool,
 	return false, nil
 }
 
-func (m *OrchestratorService) createWindowWithPanels(sessionName string, dirPath string, window conf.WindowConfig, env map[string]string) error {
+// projectDir returns the directory of the item. Items of running sessions
+// carry the session name as their path, so the path of the session is used.
+func (m *OrchestratorService) projectDir(item *dataproviders.Item, sessionName string) (string, error) {
+	if filepath.IsAbs(item.Path) {
+		return item.Path, nil
+	}
+	return m.tmux.SessionPath(sessionName)
+}
+
+// ReconcileSession compares the windows of a running session with the project
+// config and creates the windows and panels that are missing
+func (m *OrchestratorService) ReconcileSession(item *dataproviders.Item, projectConfig conf.ProjectConfig) (*ReconcileResult, error) {
+	sessionName := SessionName(item, projectConfig)
+
+	windows, err := m.tmux.ListWindows(sessionName)
+	if err != nil {
+		return nil, err
+	}
+
+	dirPath, err := m.projectDir(item, sessionName)
+	if err != nil {
+		return nil, err
+	}
+	resolved := *item
+	resolved.Path = dirPath
+
+	live := make(map[string]bool, len(windows))
+	activeWindow := ""
+	for _, window := range windows {
+		live[window.Name] = true
+		if window.Active {
+			activeWindow = window.Name
+		}
+	}
+
+	data := conf.NewTemplateData(&resolved, sessionName, projectConfig.Env)
+	result := &ReconcileResult{}
+	configured := make(map[string]bool, len(projectConfig.WindowConfig))
+	for _, window := range projectConfig.WindowConfig {
+		configured[window.WindowName] = true
+		if live[window.WindowName] {
+			continue
+		}
+
+		logger.Printf("Creating missing window %s in session %s\n", window.WindowName, sessionName)
+		if err := m.createWindowWithPanels(sessionName, dirPath, window, projectConfig.Env, data); err != nil {
+			return result, err
+		}
+		live[window.WindowName] = true
+		result.Created = append(result.Created, window.WindowName)
+	}
+
+	for _, window := range windows {
+		if !configured[window.Name] {
+			result.Extra = append(result.Extra, window.Name)
+		}
+	}
+
+	// Creating windows selects them, so restore the window the user was on
+	if len(result.Created) > 0 && activeWindow != "" {
+		target := fmt.Sprintf("%s:%s", sessionName, activeWindow)
+		if err := m.tmux.FocusWindow(target); err != nil {
+			return result, fmt.Errorf("Failed to focus window %s: %w", activeWindow, err)
+		}
+	}
+
+	return result, nil
+}
+
+// fireHook runs the hook command if one is configured. A failing hook does not
+// fail the session operation, but is logged and shown in tmux.
+func (m *OrchestratorService) fireHook(event string, command string, sessionName string, dirPath string) {
+	if command == "" {
+		return
+	}
+
+	logger.Printf("Running %s hook for session %s\n", event, sessionName)
+	hook := hooks.Hook{Event: event, Command: command, Session: sessionName, Path: dirPath}
+	if err := m.runHook(hook); err != nil {
+		logger.Printf("Hook failed for session %s: %v\n", sessionName, err)
+		if displayErr := m.tmux.DisplayMessage(fmt.Sprintf("mux-session: %v", err)); displayErr != nil {
+			logger.Printf("Failed to display hook error: %v\n", displayErr)
+		}
+	}
+}
+
+// reconcile runs ReconcileSession and only logs its outcome, as a failed
+// reconcile should not prevent switching to the session
+func (m *OrchestratorService) reconcile(item *dataproviders.Item, projectConfig conf.ProjectConfig) {
+	sessionName := SessionName(item, projectConfig)
+	result, err := m.ReconcileSession(item, projectConfig)
+	if err != nil {
+		logger.Printf("Failed to reconcile session %s: %v\n", sessionName, err)
+		return
+	}
+
+	for _, window := range result.Extra {
+		logger.Printf("Window %s of session %s is not in the project config\n", window, sessionName)
+	}
+}
+
+func (m *OrchestratorService) createWindowWithPanels(sessionName string, dirPath string, window conf.WindowConfig, env map[string]string, data conf.TemplateData) error {
 	target := fmt.Sprintf("%s:", sessionName)
 
 	if err := m.tmux.CreateWindow(target, window.WindowName, dirPath, env); err != nil {
@@ -102,7 +330,7 @@ func (m *OrchestratorService) createWindowWithPanels(sessionName string, dirPath
 	}
 
 	if len(window.PanelConfig) > 0 {
-		if err := m.setupPanels(sessionName, window.WindowName, dirPath, window.PanelConfig); err != nil {
+		if err := m.setupPanels(sessionName, window.WindowName, dirPath, window.PanelConfig, data); err != nil {
 			return fmt.Errorf("failed to setup panels for window %s: %w", window.WindowName, err)
 		}
 	}
@@ -111,7 +339,7 @@ func (m *OrchestratorService) createWindowWithPanels(sessionName string, dirPath
 	if window.Cmd != nil && *window.Cmd != "" {
 		target := fmt.Sprintf("%s:%s", sessionName, window.WindowName)
 
-		if err := m.tmux.SendKeys(target, *window.Cmd); err != nil {
+		if err := m.sendCommand(target, *window.Cmd, data); err != nil {
 			return fmt.Errorf("failed to send command to window %s: %w", window.WindowName, err)
 		}
 	}
@@ -119,7 +347,7 @@ func (m *OrchestratorService) createWindowWithPanels(sessionName string, dirPath
 	return nil
 }
 
-func (m *OrchestratorService) setupPanels(sessionName, windowName, dirPath string, panels []conf.PanelConfig) error {
+func (m *OrchestratorService) setupPanels(sessionName, windowName, dirPath string, panels []conf.PanelConfig, data conf.TemplateData) error {
 	if len(panels) == 0 {
 		return nil
 	}
@@ -130,8 +358,8 @@ func (m *OrchestratorService) setupPanels(sessionName, windowName, dirPath strin
 	if len(panels) == 1 {
 		// Just execute the command for the single panel
 		if panels[0].Cmd != "" {
-			if err := m.tmux.SendKeys(target, panels[0].Cmd); err != nil {
-				return fmt.Errorf("failed to send command to panel: %w", err)
+			if err := m.sendCommand(target, panels[0].Cmd, data); err != nil {
+				return fmt.Errorf("failed to send command to panel 0: %w", err)
 			}
 		}
 
@@ -140,8 +368,8 @@ func (m *OrchestratorService) setupPanels(sessionName, windowName, dirPath strin
 
 	// Execute command for first panel if specified
 	if panels[0].Cmd != "" {
-		if err := m.tmux.SendKeys(target, panels[0].Cmd); err != nil {
-			return fmt.Errorf("failed to send command to panel: %w", err)
+		if err := m.sendCommand(target, panels[0].Cmd, data); err != nil {
+			return fmt.Errorf("failed to send command to panel 0: %w", err)
 		}
 	}
 
@@ -150,7 +378,7 @@ func (m *OrchestratorService) setupPanels(sessionName, windowName, dirPath strin
 			return fmt.Errorf("failed to create split for panel %d: %w", i+1, err)
 		}
 
-		if err := m.tmux.SendKeys(target, panel.Cmd); err != nil {
+		if err := m.sendCommand(target, panel.Cmd, data); err != nil {
 			return fmt.Errorf("failed to send command to panel %d: %w", i+1, err)
 		}
 	}
@@ -158,6 +386,17 @@ func (m *OrchestratorService) setupPanels(sessionName, windowName, dirPath strin
 	return nil
 }
 
+// sendCommand expands the template variables of a window or panel command and
+// sends it to the target
+func (m *OrchestratorService) sendCommand(target string, cmd string, data conf.TemplateData) error {
+	expanded, err := conf.ExpandCommand(cmd, data)
+	if err != nil {
+		return err
+	}
+
+	return m.tmux.SendKeys(target, expanded)
+}
+
 func (m *OrchestratorService) findPrimaryWindow(windows []conf.WindowConfig) string {
 	for _, window := range windows {
 		if window.Primary != nil && *window.Primary {
@@ -0,0 +1,458 @@
+package orchestrator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/niedch/mux-session/internal/conf"
+	"github.com/niedch/mux-session/internal/dataproviders"
+	"github.com/niedch/mux-session/internal/hooks"
+	"github.com/niedch/mux-session/internal/tmux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSession(t *testing.T) {
+	item := &dataproviders.Item{Id: "my-project", Path: "/home/user/my-project"}
+
+	tests := []struct {
+		name          string
+		projectConfig conf.ProjectConfig
+		expected      *tmux.RecordedSession
+	}{
+		{
+			name: "single window with command",
+			projectConfig: conf.ProjectConfig{
+				WindowConfig: []conf.WindowConfig{
+					{WindowName: "vim", Cmd: stringPtr("vim .")},
+				},
+			},
+			expected: &tmux.RecordedSession{
+				Name: "my-project",
+				Windows: []*tmux.RecordedWindow{
+					{Name: "vim", WorkingDir: item.Path, Panes: []*tmux.RecordedPane{
+						{WorkingDir: item.Path, Keys: []string{"vim ."}},
+					}},
+				},
+				ActiveWindow: "vim",
+			},
+		},
+		{
+			name: "project name and env apply to every window",
+			projectConfig: conf.ProjectConfig{
+				Name: stringPtr("custom"),
+				Env:  map[string]string{"FOO": "bar"},
+				WindowConfig: []conf.WindowConfig{
+					{WindowName: "vim"},
+					{WindowName: "shell"},
+				},
+			},
+			expected: &tmux.RecordedSession{
+				Name: "custom",
+				Env:  map[string]string{"FOO": "bar"},
+				Windows: []*tmux.RecordedWindow{
+					{Name: "vim", WorkingDir: item.Path, Env: map[string]string{"FOO": "bar"}, Panes: []*tmux.RecordedPane{
+						{WorkingDir: item.Path},
+					}},
+					{Name: "shell", WorkingDir: item.Path, Env: map[string]string{"FOO": "bar"}, Panes: []*tmux.RecordedPane{
+						{WorkingDir: item.Path},
+					}},
+				},
+				ActiveWindow: "shell",
+			},
+		},
+		{
+			name: "commands are expanded as templates",
+			projectConfig: conf.ProjectConfig{
+				Env: map[string]string{"EDITOR": "nvim"},
+				WindowConfig: []conf.WindowConfig{
+					{WindowName: "vim", Cmd: stringPtr("{{.Env.EDITOR}} {{.Path}}"), PanelConfig: []conf.PanelConfig{
+						{PanelDirection: "h", Cmd: "echo {{.Name}}"},
+					}},
+				},
+			},
+			expected: &tmux.RecordedSession{
+				Name: "my-project",
+				Env:  map[string]string{"EDITOR": "nvim"},
+				Windows: []*tmux.RecordedWindow{
+					{Name: "vim", WorkingDir: item.Path, Env: map[string]string{"EDITOR": "nvim"}, Panes: []*tmux.RecordedPane{
+						{WorkingDir: item.Path, Keys: []string{"echo my-project", "nvim /home/user/my-project"}},
+					}},
+				},
+				ActiveWindow: "vim",
+			},
+		},
+		{
+			name: "primary window is focused",
+			projectConfig: conf.ProjectConfig{
+				WindowConfig: []conf.WindowConfig{
+					{WindowName: "vim"},
+					{WindowName: "editor", Primary: boolPtr(true)},
+					{WindowName: "shell"},
+				},
+			},
+			expected: &tmux.RecordedSession{
+				Name: "my-project",
+				Windows: []*tmux.RecordedWindow{
+					{Name: "vim", WorkingDir: item.Path, Panes: []*tmux.RecordedPane{{WorkingDir: item.Path}}},
+					{Name: "editor", WorkingDir: item.Path, Panes: []*tmux.RecordedPane{{WorkingDir: item.Path}}},
+					{Name: "shell", WorkingDir: item.Path, Panes: []*tmux.RecordedPane{{WorkingDir: item.Path}}},
+				},
+				ActiveWindow: "editor",
+			},
+		},
+		{
+			name: "panels are split and run their commands before the window command",
+			projectConfig: conf.ProjectConfig{
+				WindowConfig: []conf.WindowConfig{
+					{
+						WindowName: "perf",
+						Cmd:        stringPtr("echo done"),
+						PanelConfig: []conf.PanelConfig{
+							{PanelDirection: "h", Cmd: "top"},
+							{PanelDirection: "v", Cmd: "htop"},
+						},
+					},
+				},
+			},
+			expected: &tmux.RecordedSession{
+				Name: "my-project",
+				Windows: []*tmux.RecordedWindow{
+					{Name: "perf", WorkingDir: item.Path, Panes: []*tmux.RecordedPane{
+						{WorkingDir: item.Path, Keys: []string{"top"}},
+						{Direction: "v", WorkingDir: item.Path, Keys: []string{"htop", "echo done"}},
+					}, ActivePane: 1},
+				},
+				ActiveWindow: "perf",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tmux.NewRecorder()
+			service := New(recorder)
+
+			require.NoError(t, service.CreateSession(item, tt.projectConfig))
+
+			assert.Equal(t, tt.expected, recorder.Session(tt.expected.Name))
+			assert.Equal(t, tt.expected.Name, recorder.CurrentSession)
+		})
+	}
+}
+
+func TestCreateSession_NoWindows(t *testing.T) {
+	recorder := tmux.NewRecorder()
+	service := New(recorder)
+
+	err := service.CreateSession(&dataproviders.Item{Id: "my-project", Path: "/tmp/my-project"}, conf.ProjectConfig{})
+
+	assert.Error(t, err)
+	assert.Empty(t, recorder.Sessions)
+}
+
+func TestCreateSession_RollsBackOnFailure(t *testing.T) {
+	item := &dataproviders.Item{Id: "my-project", Path: "/tmp/my-project"}
+	projectConfig := conf.ProjectConfig{
+		WindowConfig: []conf.WindowConfig{
+			{WindowName: "vim"},
+			{WindowName: "shell", Cmd: stringPtr("make")},
+			{WindowName: "perf", PanelConfig: []conf.PanelConfig{
+				{PanelDirection: "h", Cmd: "top"},
+				{PanelDirection: "x", Cmd: "htop"},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		failOn   func(r *tmux.Recorder)
+		expected string
+	}{
+		{
+			name:     "invalid panel direction",
+			failOn:   func(r *tmux.Recorder) {},
+			expected: "failed to setup panels for window perf: failed to create split for panel 1",
+		},
+		{
+			name: "send-keys error",
+			failOn: func(r *tmux.Recorder) {
+				r.FailOn("SendKeys", "my-project:shell", errors.New("send-keys failed"))
+			},
+			expected: "failed to send command to window shell: send-keys failed",
+		},
+		{
+			name: "window creation error",
+			failOn: func(r *tmux.Recorder) {
+				r.FailOn("CreateWindow", "my-project:shell", errors.New("new-window failed"))
+			},
+			expected: "failed to create window shell",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tmux.NewRecorder()
+			tt.failOn(recorder)
+
+			err := New(recorder).CreateSession(item, projectConfig)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+			assert.Nil(t, recorder.Session("my-project"), "partial session should be killed")
+			assert.Empty(t, recorder.CurrentSession)
+		})
+	}
+}
+
+func TestCreateSession_KeepPartial(t *testing.T) {
+	recorder := tmux.NewRecorder()
+	recorder.FailOn("SendKeys", "my-project:shell", errors.New("send-keys failed"))
+	projectConfig := conf.ProjectConfig{
+		WindowConfig: []conf.WindowConfig{
+			{WindowName: "vim"},
+			{WindowName: "shell", Cmd: stringPtr("make")},
+		},
+	}
+
+	err := New(recorder).WithKeepPartial(true).CreateSession(&dataproviders.Item{Id: "my-project", Path: "/tmp/my-project"}, projectConfig)
+
+	require.Error(t, err)
+	session := recorder.Session("my-project")
+	require.NotNil(t, session, "partial session should be kept")
+	assert.Len(t, session.Windows, 2)
+	assert.Empty(t, recorder.CurrentSession)
+}
+
+func TestSwitchSession(t *testing.T) {
+	recorder := tmux.NewRecorder()
+	service := New(recorder)
+	item := &dataproviders.Item{Id: "my-project", Path: "/tmp/my-project"}
+
+	ok, err := service.SwitchSession(item, conf.ProjectConfig{})
+	require.NoError(t, err, "no server running should not be an error")
+	assert.False(t, ok)
+
+	require.NoError(t, recorder.NewSession("my-project", "vim", item.Path, nil))
+
+	ok, err = service.SwitchSession(item, conf.ProjectConfig{})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "my-project", recorder.CurrentSession)
+}
+
+func TestSwitchSession_ConfiguredName(t *testing.T) {
+	recorder := tmux.NewRecorder()
+	item := &dataproviders.Item{Id: "my-project", Path: "/tmp/my-project"}
+	require.NoError(t, recorder.NewSession("my-project", "vim", item.Path, nil))
+	require.NoError(t, recorder.NewSession("work", "vim", item.Path, nil))
+
+	ok, err := New(recorder).SwitchSession(item, conf.ProjectConfig{Name: stringPtr("work")})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "work", recorder.CurrentSession, "the configured name should be switched to, not the id")
+}
+
+func TestSwitchSession_DottedId(t *testing.T) {
+	recorder := tmux.NewRecorder()
+	item := &dataproviders.Item{Id: "org/api.v2", Path: "/tmp/api.v2"}
+	require.NoError(t, recorder.NewSession("org/api_v2", "vim", item.Path, nil))
+
+	ok, err := New(recorder).SwitchSession(item, conf.ProjectConfig{})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "org/api_v2", recorder.CurrentSession, "the session should be found by the name tmux gave it")
+}
+
+func TestEnsureSession(t *testing.T) {
+	recorder := tmux.NewRecorder()
+	service := New(recorder)
+	item := &dataproviders.Item{Id: "my-project", Path: "/tmp/my-project"}
+	projectConfig := conf.ProjectConfig{WindowConfig: []conf.WindowConfig{{WindowName: "vim"}}}
+
+	created, err := service.EnsureSession(item, projectConfig)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.NotNil(t, recorder.Session("my-project"))
+	assert.Empty(t, recorder.CurrentSession, "the new session must not be switched to")
+
+	created, err = service.EnsureSession(item, projectConfig)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Len(t, recorder.Sessions, 1)
+
+	t.Run("configured name", func(t *testing.T) {
+		projectConfig.Name = stringPtr("work")
+
+		created, err := service.EnsureSession(item, projectConfig)
+		require.NoError(t, err)
+		assert.True(t, created, "the configured name is not running yet")
+
+		created, err = service.EnsureSession(item, projectConfig)
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Len(t, recorder.Sessions, 2)
+	})
+}
+
+func TestReconcileSession(t *testing.T) {
+	item := &dataproviders.Item{Id: "my-project", Path: "/tmp/my-project"}
+	recorder := tmux.NewRecorder()
+	require.NoError(t, recorder.NewSession("my-project", "vim", item.Path, nil))
+	require.NoError(t, recorder.CreateWindow("my-project:", "scratch", item.Path, nil))
+	require.NoError(t, recorder.FocusWindow("my-project:vim"))
+
+	projectConfig := conf.ProjectConfig{
+		Env: map[string]string{"FOO": "bar"},
+		WindowConfig: []conf.WindowConfig{
+			{WindowName: "vim"},
+			{WindowName: "runner", Cmd: stringPtr("make watch")},
+			{WindowName: "perf", PanelConfig: []conf.PanelConfig{
+				{PanelDirection: "h", Cmd: "top"},
+				{PanelDirection: "h", Cmd: "htop"},
+			}},
+		},
+	}
+
+	result, err := New(recorder).ReconcileSession(item, projectConfig)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"runner", "perf"}, result.Created)
+	assert.Equal(t, []string{"scratch"}, result.Extra)
+
+	session := recorder.Session("my-project")
+	require.Len(t, session.Windows, 4, "extra windows must not be killed")
+	assert.Equal(t, "vim", session.ActiveWindow, "focus should be restored")
+	assert.Equal(t, []string{"make watch"}, session.Window("runner").Panes[0].Keys)
+	assert.Equal(t, map[string]string{"FOO": "bar"}, session.Window("runner").Env)
+	assert.Len(t, session.Window("perf").Panes, 2)
+
+	result, err = New(recorder).ReconcileSession(item, projectConfig)
+	require.NoError(t, err)
+	assert.Empty(t, result.Created, "second reconcile should be a no-op")
+}
+
+func TestReconcileSession_RunningSessionItem(t *testing.T) {
+	// Items of the tmux provider carry the session name as their path
+	item := &dataproviders.Item{Id: "scratch", Path: "scratch"}
+	recorder := tmux.NewRecorder()
+	require.NoError(t, recorder.NewSession("scratch", "vim", "/tmp/scratch", nil))
+
+	result, err := New(recorder).ReconcileSession(item, conf.ProjectConfig{
+		WindowConfig: []conf.WindowConfig{
+			{WindowName: "vim"},
+			{WindowName: "runner", Cmd: stringPtr("ls {{.Path}}")},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"runner"}, result.Created)
+
+	runner := recorder.Session("scratch").Window("runner")
+	assert.Equal(t, "/tmp/scratch", runner.WorkingDir)
+	assert.Equal(t, []string{"ls /tmp/scratch"}, runner.Panes[0].Keys)
+}
+
+func TestSwitchSession_ReconcileOnSwitch(t *testing.T) {
+	item := &dataproviders.Item{Id: "my-project", Path: "/tmp/my-project"}
+	recorder := tmux.NewRecorder()
+	require.NoError(t, recorder.NewSession("my-project", "vim", item.Path, nil))
+
+	projectConfig := conf.ProjectConfig{
+		WindowConfig: []conf.WindowConfig{
+			{WindowName: "vim"},
+			{WindowName: "runner"},
+		},
+	}
+
+	ok, err := New(recorder).SwitchSession(item, projectConfig)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Nil(t, recorder.Session("my-project").Window("runner"), "reconcile is off by default")
+
+	ok, err = New(recorder).WithReconcileOnSwitch(true).SwitchSession(item, projectConfig)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.NotNil(t, recorder.Session("my-project").Window("runner"))
+}
+
+func TestCreateSession_Hooks(t *testing.T) {
+	item := &dataproviders.Item{Id: "my-project", Path: "/tmp/my-project"}
+	projectConfig := conf.ProjectConfig{
+		WindowConfig: []conf.WindowConfig{{WindowName: "vim"}},
+		OnCreate:     "direnv allow",
+		OnSwitch:     "touch .switched",
+		OnDetach:     "docker compose stop",
+	}
+
+	var ran []hooks.Hook
+	recorder := tmux.NewRecorder()
+	service := New(recorder).WithHookRunner(func(h hooks.Hook) error {
+		ran = append(ran, h)
+		return nil
+	})
+
+	require.NoError(t, service.CreateSession(item, projectConfig))
+
+	assert.Equal(t, []hooks.Hook{
+		{Event: hooks.OnCreate, Command: "direnv allow", Session: "my-project", Path: item.Path},
+	}, ran)
+	detach := hooks.Hook{Event: hooks.OnDetach, Command: "docker compose stop", Session: "my-project", Path: item.Path}
+	assert.Equal(t, map[string]string{"client-detached": detach.Script()}, recorder.Session("my-project").Hooks)
+
+	ran = nil
+	ok, err := service.SwitchSession(item, projectConfig)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []hooks.Hook{
+		{Event: hooks.OnSwitch, Command: "touch .switched", Session: "my-project", Path: item.Path},
+	}, ran)
+}
+
+func TestSwitchSession_HookOfRunningSession(t *testing.T) {
+	// Items of the tmux provider carry the session name as their path
+	item := &dataproviders.Item{Id: "scratch", Path: "scratch"}
+	recorder := tmux.NewRecorder()
+	require.NoError(t, recorder.NewSession("scratch", "vim", "/tmp/scratch", nil))
+
+	var ran []hooks.Hook
+	var switchedBefore []string
+	service := New(recorder).WithHookRunner(func(h hooks.Hook) error {
+		ran = append(ran, h)
+		switchedBefore = append(switchedBefore, recorder.CurrentSession)
+		return nil
+	})
+
+	ok, err := service.SwitchSession(item, conf.ProjectConfig{OnSwitch: "touch .switched"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []hooks.Hook{
+		{Event: hooks.OnSwitch, Command: "touch .switched", Session: "scratch", Path: "/tmp/scratch"},
+	}, ran)
+	assert.Equal(t, []string{""}, switchedBefore, "the hook should run before switching, which blocks outside of tmux")
+	assert.Equal(t, "scratch", recorder.CurrentSession)
+}
+
+func TestCreateSession_HookFailureIsDisplayed(t *testing.T) {
+	recorder := tmux.NewRecorder()
+	service := New(recorder).WithHookRunner(func(h hooks.Hook) error {
+		return errors.New("on_create hook failed: exit status 1")
+	})
+
+	err := service.CreateSession(&dataproviders.Item{Id: "my-project", Path: "/tmp/my-project"}, conf.ProjectConfig{
+		WindowConfig: []conf.WindowConfig{{WindowName: "vim"}},
+		OnCreate:     "false",
+	})
+
+	require.NoError(t, err, "a failing hook should not fail the session")
+	assert.NotNil(t, recorder.Session("my-project"))
+	assert.Equal(t, "my-project", recorder.CurrentSession)
+	assert.Equal(t, []string{"mux-session: on_create hook failed: exit status 1"}, recorder.Messages)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
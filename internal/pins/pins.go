@@ -0,0 +1,119 @@
+package pins
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/adrg/xdg"
+)
+
+// Store holds the Ids of the pinned projects, in the order they were pinned
+type Store struct {
+	path string
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// DefaultStore keeps the pins in XDG_DATA_HOME/mux-session/pins
+func DefaultStore() (*Store, error) {
+	path, err := xdg.DataFile(filepath.Join("mux-session", "pins"))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStore(path), nil
+}
+
+// List returns the pinned Ids
+func (s *Store) List() ([]string, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, scanner.Err()
+}
+
+// Set returns the pinned Ids as a set
+func (s *Store) Set() (map[string]bool, error) {
+	ids, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set, nil
+}
+
+// Pin adds id to the pins, pinning it twice has no effect
+func (s *Store) Pin(id string) error {
+	ids, err := s.List()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(ids, id) {
+		return nil
+	}
+
+	return s.write(append(ids, id))
+}
+
+// Unpin removes id from the pins
+func (s *Store) Unpin(id string) error {
+	ids, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	return s.write(slices.DeleteFunc(ids, func(pinned string) bool {
+		return pinned == id
+	}))
+}
+
+// Toggle pins id if it is not pinned and unpins it otherwise. It returns
+// whether id is pinned afterwards.
+func (s *Store) Toggle(id string) (bool, error) {
+	ids, err := s.List()
+	if err != nil {
+		return false, err
+	}
+
+	if slices.Contains(ids, id) {
+		return false, s.Unpin(id)
+	}
+	return true, s.Pin(id)
+}
+
+func (s *Store) write(ids []string) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	for _, id := range ids {
+		content.WriteString(id + "\n")
+	}
+
+	return os.WriteFile(s.path, []byte(content.String()), 0o600)
+}
@@ -0,0 +1,44 @@
+package pins
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "mux-session", "pins"))
+
+	ids, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, ids, "without a file nothing is pinned")
+
+	require.NoError(t, store.Pin("api"))
+	require.NoError(t, store.Pin("web"))
+	require.NoError(t, store.Pin("api"))
+
+	ids, err = store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api", "web"}, ids, "pins keep their order and are not duplicated")
+
+	pinned, err := store.Toggle("api")
+	require.NoError(t, err)
+	assert.False(t, pinned)
+
+	pinned, err = store.Toggle("docs")
+	require.NoError(t, err)
+	assert.True(t, pinned)
+
+	set, err := store.Set()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"web": true, "docs": true}, set)
+
+	require.NoError(t, store.Unpin("web"))
+	require.NoError(t, store.Unpin("missing"))
+
+	ids, err = store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs"}, ids)
+}
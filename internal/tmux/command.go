@@ -1,28 +1,74 @@
 package tmux
 
 import (
+	"bytes"
+	"errors"
+	"os"
 	"os/exec"
 	"strings"
 )
 
+// Runner executes tmux commands, e.g. over a control mode connection
+type Runner interface {
+	Run(c *Command) ([]byte, error)
+}
+
 type Command struct {
-	name   string
-	args   []string
-	output bool
-	socket string
+	name        string
+	args        []string
+	output      bool
+	interactive bool
+	socket      string
+	runner      Runner
 }
 
 func (c *Command) Exec() ([]byte, error) {
+	if c.runner != nil {
+		return c.runner.Run(c)
+	}
+	return c.execProcess()
+}
+
+// String returns the command as a line for the tmux command parser
+func (c *Command) String() string {
+	parts := []string{c.name}
+	for _, arg := range c.args {
+		parts = append(parts, quoteArg(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (c *Command) execProcess() ([]byte, error) {
 	args := []string{c.name}
 	if c.socket != "" {
 		args = append([]string{"-L", c.socket}, args...)
 	}
 	args = append(args, c.args...)
 	cmd := exec.Command("tmux", args...)
+
+	if c.interactive {
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		return nil, cmd.Run()
+	}
+
+	var stdout, stderr bytes.Buffer
 	if c.output {
-		return cmd.Output()
+		cmd.Stdout = &stdout
 	}
-	return nil, cmd.Run()
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, newError(c, exitErr.ExitCode(), stderr.String())
+		}
+		return nil, err
+	}
+
+	if !c.output {
+		return nil, nil
+	}
+	return stdout.Bytes(), nil
 }
 
 type OptFunc func(*Command)
@@ -57,12 +103,25 @@ func WithOutput() OptFunc {
 	}
 }
 
+// WithInteractive connects the command to the terminal, e.g. for attaching
+func WithInteractive() OptFunc {
+	return func(c *Command) {
+		c.interactive = true
+	}
+}
+
 func WithSocket(socket string) OptFunc {
 	return func(c *Command) {
 		c.socket = socket
 	}
 }
 
+func WithRunner(runner Runner) OptFunc {
+	return func(c *Command) {
+		c.runner = runner
+	}
+}
+
 func NewCommand(name string, opts ...OptFunc) *Command {
 	cmd := &Command{
 		name: name,
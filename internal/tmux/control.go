@@ -0,0 +1,217 @@
+package tmux
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const controlStartTimeout = 2 * time.Second
+
+var ErrControlClosed = errors.New("tmux control mode connection closed")
+
+type controlReply struct {
+	output []string
+	failed bool
+}
+
+// ControlClient keeps a single tmux control mode (-C) client open and sends
+// commands over it instead of spawning a tmux process per call.
+type ControlClient struct {
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	replies chan controlReply
+	done    chan struct{}
+}
+
+// NewControlClient attaches a control mode client to the server behind socket.
+// It fails if no session exists to attach to.
+func NewControlClient(socket string) (*ControlClient, error) {
+	var args []string
+	if socket != "" {
+		args = append(args, "-L", socket)
+	}
+	args = append(args, "-C", "attach-session")
+
+	cmd := exec.Command("tmux", args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start tmux control mode: %w", err)
+	}
+
+	c := &ControlClient{
+		cmd:     cmd,
+		stdin:   stdin,
+		replies: make(chan controlReply),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(stdout)
+
+	// The attach-session command itself produces the first reply
+	select {
+	case reply := <-c.replies:
+		if reply.failed {
+			c.Close()
+			return nil, fmt.Errorf("failed to attach tmux control mode: %s", strings.Join(reply.output, "\n"))
+		}
+	case <-c.done:
+		c.Close()
+		return nil, ErrControlClosed
+	case <-time.After(controlStartTimeout):
+		cmd.Process.Kill()
+		c.Close()
+		return nil, errors.New("timed out waiting for tmux control mode")
+	}
+
+	return c, nil
+}
+
+// Run sends the command over the control connection. Once the connection is
+// gone, commands fall back to spawning a tmux process.
+func (c *ControlClient) Run(cmd *Command) ([]byte, error) {
+	// Interactive commands need the terminal, which the control client lacks
+	if cmd.interactive {
+		return cmd.execProcess()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return cmd.execProcess()
+	default:
+	}
+
+	if _, err := io.WriteString(c.stdin, cmd.String()+"\n"); err != nil {
+		return cmd.execProcess()
+	}
+
+	select {
+	case reply := <-c.replies:
+		if reply.failed {
+			// Control mode has no exit code, tmux itself would have exited with 1
+			return nil, newError(cmd, 1, strings.Join(reply.output, "\n"))
+		}
+		if !cmd.output {
+			return nil, nil
+		}
+		return []byte(strings.Join(reply.output, "\n")), nil
+	case <-c.done:
+		return nil, ErrControlClosed
+	}
+}
+
+// Close detaches the control client and waits for it to exit
+func (c *ControlClient) Close() error {
+	c.stdin.Close()
+	<-c.done
+
+	// tmux exits with a non-zero status once the control client detaches
+	if err := c.cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ControlClient) readLoop(r io.Reader) {
+	defer close(c.done)
+	parseReplies(r, c.replies)
+}
+
+// parseReplies reads control mode output and sends every %begin/%end or
+// %begin/%error block to replies. Notifications outside of a block are
+// dropped, and after the initial block only replies to commands sent by this
+// client are delivered.
+func parseReplies(r io.Reader, replies chan<- controlReply) {
+	reader := bufio.NewReader(r)
+	started := false
+
+	var block *controlReply
+	var guard string
+	var own bool
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		if block == nil {
+			if strings.HasPrefix(line, "%begin ") {
+				block = &controlReply{}
+				guard, own = parseGuard(line)
+			}
+			continue
+		}
+
+		isEnd := strings.HasPrefix(line, "%end ")
+		isError := strings.HasPrefix(line, "%error ")
+		if isEnd || isError {
+			if g, _ := parseGuard(line); g == guard {
+				block.failed = isError
+				if !started || own {
+					replies <- *block
+				}
+				started = true
+				block = nil
+				continue
+			}
+		}
+
+		block.output = append(block.output, line)
+	}
+}
+
+// parseGuard returns the time and command number identifying a block and
+// whether the command was sent by this client.
+func parseGuard(line string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return "", false
+	}
+	flags, _ := strconv.Atoi(fields[3])
+	return fields[1] + " " + fields[2], flags&1 == 1
+}
+
+// quoteArg quotes an argument for the tmux command parser
+func quoteArg(arg string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range arg {
+		switch r {
+		case '"', '\\', '$':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,50 @@
+package tmux
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReplies(t *testing.T) {
+	output := strings.Join([]string{
+		"%begin 1700000000 10 0",
+		"%end 1700000000 10 0",
+		"%session-changed $0 main",
+		"%begin 1700000001 11 1",
+		"main",
+		"%end is part of the output",
+		"other",
+		"%end 1700000001 11 1",
+		"%begin 1700000002 12 0",
+		"from a hook",
+		"%end 1700000002 12 0",
+		"%output %1 hello",
+		"%begin 1700000003 13 1",
+		"can't find pane: nope",
+		"%error 1700000003 13 1",
+		"",
+	}, "\n")
+
+	replies := make(chan controlReply, 10)
+	parseReplies(strings.NewReader(output), replies)
+	close(replies)
+
+	var got []controlReply
+	for reply := range replies {
+		got = append(got, reply)
+	}
+
+	assert.Equal(t, []controlReply{
+		{},
+		{output: []string{"main", "%end is part of the output", "other"}},
+		{output: []string{"can't find pane: nope"}, failed: true},
+	}, got)
+}
+
+func TestCommandString(t *testing.T) {
+	cmd := NewCommand("send-keys", WithTarget("my session:0"), WithKey("echo \"$HOME\"\ngit fetch"), WithEnter())
+
+	assert.Equal(t, `send-keys "-t" "my session:0" "echo \"\$HOME\"\ngit fetch" "C-m"`, cmd.String())
+}
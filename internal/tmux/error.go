@@ -0,0 +1,66 @@
+package tmux
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrDuplicateSession = errors.New("duplicate session")
+	ErrNoServer         = errors.New("no server running")
+	ErrNoCurrentClient  = errors.New("no current client")
+	ErrNoTerminal       = errors.New("no terminal to attach to")
+)
+
+// Error is returned when a tmux command fails. Kind is one of the Err*
+// sentinels if the failure could be classified, so callers can use errors.Is.
+type Error struct {
+	Command  string
+	Args     []string
+	ExitCode int
+	Stderr   string
+	Kind     error
+}
+
+func newError(c *Command, exitCode int, stderr string) *Error {
+	stderr = strings.TrimSpace(stderr)
+	return &Error{
+		Command:  c.name,
+		Args:     c.args,
+		ExitCode: exitCode,
+		Stderr:   stderr,
+		Kind:     classify(stderr),
+	}
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("tmux %s %s: exit status %d", e.Command, strings.Join(e.Args, " "), e.ExitCode)
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func classify(stderr string) error {
+	switch {
+	case strings.HasPrefix(stderr, "can't find session"),
+		strings.HasPrefix(stderr, "session not found"):
+		return ErrSessionNotFound
+	case strings.HasPrefix(stderr, "duplicate session"):
+		return ErrDuplicateSession
+	case strings.HasPrefix(stderr, "no server running"),
+		strings.HasPrefix(stderr, "error connecting to"),
+		strings.HasPrefix(stderr, "server exited unexpectedly"):
+		return ErrNoServer
+	case strings.HasPrefix(stderr, "no current client"):
+		return ErrNoCurrentClient
+	}
+
+	return nil
+}
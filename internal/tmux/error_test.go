@@ -0,0 +1,39 @@
+package tmux
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewError_Classification(t *testing.T) {
+	tests := []struct {
+		stderr string
+		kind   error
+	}{
+		{stderr: "can't find session: my-project\n", kind: ErrSessionNotFound},
+		{stderr: "duplicate session: my-project\n", kind: ErrDuplicateSession},
+		{stderr: "no server running on /tmp/tmux-1000/default\n", kind: ErrNoServer},
+		{stderr: "error connecting to /tmp/tmux-1000/test (No such file or directory)\n", kind: ErrNoServer},
+		{stderr: "no current client\n", kind: ErrNoCurrentClient},
+		{stderr: "unknown command: foo\n", kind: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stderr, func(t *testing.T) {
+			err := newError(NewCommand("switch-client", WithTarget("my-project")), 1, tt.stderr)
+
+			assert.Equal(t, tt.kind, err.Kind)
+			if tt.kind != nil {
+				assert.True(t, errors.Is(err, tt.kind))
+			}
+		})
+	}
+}
+
+func TestError_Message(t *testing.T) {
+	err := newError(NewCommand("new-window", WithTarget("my-project:"), WithWindowName("vim")), 1, "can't find session: my-project\n")
+
+	assert.Equal(t, "tmux new-window -t my-project: -n vim: exit status 1: can't find session: my-project", err.Error())
+}
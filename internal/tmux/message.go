@@ -19,3 +19,7 @@ func WithKey(key string) OptFunc {
 func WithEnter() OptFunc {
 	return WithArg("C-m")
 }
+
+func SetHook(opts ...OptFunc) error {
+	return Exec("set-hook", opts...)
+}
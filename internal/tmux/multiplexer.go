@@ -0,0 +1,57 @@
+package tmux
+
+// Window is a window of a running session
+type Window struct {
+	Name   string
+	Active bool
+}
+
+// Multiplexer defines the session operations mux-session needs from a
+// terminal multiplexer
+type Multiplexer interface {
+	// ListSessions returns the names of all running sessions
+	ListSessions() ([]string, error)
+
+	// SessionPath returns the working directory the session was created in
+	SessionPath(sessionName string) (string, error)
+
+	// ListWindows returns the windows of the session in index order
+	ListWindows(sessionName string) ([]Window, error)
+
+	// NewSession creates a detached session with its first window
+	NewSession(sessionName string, firstWindowName string, workingDir string, env map[string]string) error
+
+	// CreateWindow adds a window to the session given by target ("session:")
+	CreateWindow(target string, windowName string, workingDir string, env map[string]string) error
+
+	// SplitWindow splits the window given by target, direction is "v" or "h"
+	SplitWindow(target string, direction string, workingDir string) error
+
+	// SendKeys types cmd into the active pane of target followed by enter
+	SendKeys(target string, cmd string) error
+
+	// FocusWindow selects the window given by target ("session:window")
+	FocusWindow(target string) error
+
+	// SwitchSession switches the current client to the session
+	SwitchSession(sessionName string) error
+
+	// KillSession destroys the session and all of its windows
+	KillSession(sessionName string) error
+
+	// RenameSession gives the session a new name
+	RenameSession(sessionName string, newName string) error
+
+	// DetachOtherClients detaches every client attached to the session except
+	// the current one
+	DetachOtherClients(sessionName string) error
+
+	// SetHook makes tmux run the shell command whenever hook fires for the
+	// session, e.g. "client-detached"
+	SetHook(sessionName string, hook string, shellCommand string) error
+
+	// DisplayMessage shows the message in the status line of the current client
+	DisplayMessage(message string) error
+}
+
+var _ Multiplexer = (*Tmux)(nil)
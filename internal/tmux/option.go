@@ -0,0 +1,13 @@
+package tmux
+
+func SetOption(opts ...OptFunc) error {
+	return Exec("set-option", opts...)
+}
+
+func ShowOptions(opts ...OptFunc) (string, error) {
+	return Output("show-options", opts...)
+}
+
+func WithGlobal() OptFunc {
+	return WithFlag("-g")
+}
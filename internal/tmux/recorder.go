@@ -0,0 +1,316 @@
+package tmux
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// RecordedPane is a pane created through the Recorder
+type RecordedPane struct {
+	// Direction is empty for the pane created together with its window
+	Direction  string
+	WorkingDir string
+	Keys       []string
+}
+
+// RecordedWindow is a window created through the Recorder
+type RecordedWindow struct {
+	Name       string
+	WorkingDir string
+	Env        map[string]string
+	Panes      []*RecordedPane
+	ActivePane int
+}
+
+// RecordedSession is a session created through the Recorder
+type RecordedSession struct {
+	Name         string
+	Env          map[string]string
+	Windows      []*RecordedWindow
+	ActiveWindow string
+	Hooks        map[string]string
+}
+
+// Recorder is an in-memory Multiplexer which records the resulting
+// session/window/pane tree instead of talking to a tmux server
+type Recorder struct {
+	Sessions       []*RecordedSession
+	CurrentSession string
+	Messages       []string
+	// Detached are the sessions whose other clients were detached
+	Detached []string
+	failures map[string]error
+}
+
+var _ Multiplexer = (*Recorder)(nil)
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// FailOn makes the operation on target return err instead of being recorded,
+// e.g. FailOn("SendKeys", "my-project:vim", err)
+func (r *Recorder) FailOn(operation string, target string, err error) {
+	if r.failures == nil {
+		r.failures = make(map[string]error)
+	}
+	r.failures[operation+" "+target] = err
+}
+
+func (r *Recorder) failure(operation string, target string) error {
+	return r.failures[operation+" "+target]
+}
+
+// Session returns the recorded session with the given name or nil
+func (r *Recorder) Session(name string) *RecordedSession {
+	for _, session := range r.Sessions {
+		if session.Name == name {
+			return session
+		}
+	}
+	return nil
+}
+
+// Window returns the window with the given name or nil
+func (s *RecordedSession) Window(name string) *RecordedWindow {
+	for _, window := range s.Windows {
+		if window.Name == name {
+			return window
+		}
+	}
+	return nil
+}
+
+func (r *Recorder) ListSessions() ([]string, error) {
+	// tmux shuts the server down once its last session is gone
+	if len(r.Sessions) == 0 {
+		return nil, ErrNoServer
+	}
+
+	sessions := make([]string, 0, len(r.Sessions))
+	for _, session := range r.Sessions {
+		sessions = append(sessions, session.Name)
+	}
+	return sessions, nil
+}
+
+func (r *Recorder) ListWindows(sessionName string) ([]Window, error) {
+	session := r.Session(sessionName)
+	if session == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionName)
+	}
+
+	windows := make([]Window, 0, len(session.Windows))
+	for _, window := range session.Windows {
+		windows = append(windows, Window{Name: window.Name, Active: window.Name == session.ActiveWindow})
+	}
+	return windows, nil
+}
+
+// SessionPath returns the working directory of the first window, which tmux
+// uses as the path of the session
+func (r *Recorder) SessionPath(sessionName string) (string, error) {
+	session := r.Session(sessionName)
+	if session == nil {
+		return "", fmt.Errorf("%w: %s", ErrSessionNotFound, sessionName)
+	}
+	return session.Windows[0].WorkingDir, nil
+}
+
+func (r *Recorder) NewSession(sessionName string, firstWindowName string, workingDir string, env map[string]string) error {
+	if err := r.failure("NewSession", sessionName); err != nil {
+		return err
+	}
+	if r.Session(sessionName) != nil {
+		return fmt.Errorf("%w: %s", ErrDuplicateSession, sessionName)
+	}
+
+	r.Sessions = append(r.Sessions, &RecordedSession{
+		Name:         sessionName,
+		Env:          maps.Clone(env),
+		Windows:      []*RecordedWindow{newRecordedWindow(firstWindowName, workingDir, env)},
+		ActiveWindow: firstWindowName,
+	})
+	return nil
+}
+
+func (r *Recorder) CreateWindow(target string, windowName string, workingDir string, env map[string]string) error {
+	if err := r.failure("CreateWindow", target+windowName); err != nil {
+		return err
+	}
+	session, _, err := r.resolve(target)
+	if err != nil {
+		return err
+	}
+
+	session.Windows = append(session.Windows, newRecordedWindow(windowName, workingDir, env))
+	session.ActiveWindow = windowName
+	return nil
+}
+
+func (r *Recorder) SplitWindow(target string, direction string, workingDir string) error {
+	if err := r.failure("SplitWindow", target); err != nil {
+		return err
+	}
+	if direction != "v" && direction != "h" {
+		return fmt.Errorf("invalid panel direction %s", direction)
+	}
+
+	_, window, err := r.resolveWindow(target)
+	if err != nil {
+		return err
+	}
+
+	window.Panes = append(window.Panes, &RecordedPane{Direction: direction, WorkingDir: workingDir})
+	window.ActivePane = len(window.Panes) - 1
+	return nil
+}
+
+func (r *Recorder) SendKeys(target string, cmd string) error {
+	if err := r.failure("SendKeys", target); err != nil {
+		return err
+	}
+	_, window, err := r.resolveWindow(target)
+	if err != nil {
+		return err
+	}
+
+	pane := window.Panes[window.ActivePane]
+	pane.Keys = append(pane.Keys, cmd)
+	return nil
+}
+
+func (r *Recorder) FocusWindow(target string) error {
+	if err := r.failure("FocusWindow", target); err != nil {
+		return err
+	}
+	session, window, err := r.resolveWindow(target)
+	if err != nil {
+		return err
+	}
+
+	session.ActiveWindow = window.Name
+	return nil
+}
+
+func (r *Recorder) SwitchSession(sessionName string) error {
+	if err := r.failure("SwitchSession", sessionName); err != nil {
+		return err
+	}
+	if r.Session(sessionName) == nil {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionName)
+	}
+
+	r.CurrentSession = sessionName
+	return nil
+}
+
+func (r *Recorder) KillSession(sessionName string) error {
+	if err := r.failure("KillSession", sessionName); err != nil {
+		return err
+	}
+
+	idx := slices.IndexFunc(r.Sessions, func(s *RecordedSession) bool { return s.Name == sessionName })
+	if idx == -1 {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionName)
+	}
+
+	r.Sessions = slices.Delete(r.Sessions, idx, idx+1)
+	if r.CurrentSession == sessionName {
+		r.CurrentSession = ""
+	}
+	return nil
+}
+
+func (r *Recorder) RenameSession(sessionName string, newName string) error {
+	if err := r.failure("RenameSession", sessionName); err != nil {
+		return err
+	}
+
+	session := r.Session(sessionName)
+	if session == nil {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionName)
+	}
+	if r.Session(newName) != nil {
+		return fmt.Errorf("%w: %s", ErrDuplicateSession, newName)
+	}
+
+	session.Name = newName
+	if r.CurrentSession == sessionName {
+		r.CurrentSession = newName
+	}
+	return nil
+}
+
+func (r *Recorder) DetachOtherClients(sessionName string) error {
+	if err := r.failure("DetachOtherClients", sessionName); err != nil {
+		return err
+	}
+	if r.Session(sessionName) == nil {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionName)
+	}
+
+	r.Detached = append(r.Detached, sessionName)
+	return nil
+}
+
+func (r *Recorder) SetHook(sessionName string, hook string, shellCommand string) error {
+	if err := r.failure("SetHook", sessionName); err != nil {
+		return err
+	}
+
+	session := r.Session(sessionName)
+	if session == nil {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionName)
+	}
+
+	if session.Hooks == nil {
+		session.Hooks = make(map[string]string)
+	}
+	session.Hooks[hook] = shellCommand
+	return nil
+}
+
+func (r *Recorder) DisplayMessage(message string) error {
+	r.Messages = append(r.Messages, message)
+	return nil
+}
+
+func newRecordedWindow(name string, workingDir string, env map[string]string) *RecordedWindow {
+	return &RecordedWindow{
+		Name:       name,
+		WorkingDir: workingDir,
+		Env:        maps.Clone(env),
+		Panes:      []*RecordedPane{{WorkingDir: workingDir}},
+	}
+}
+
+// resolve splits a "session:window" target into its session and window name
+func (r *Recorder) resolve(target string) (*RecordedSession, string, error) {
+	sessionName, windowName, _ := strings.Cut(target, ":")
+
+	session := r.Session(sessionName)
+	if session == nil {
+		return nil, "", fmt.Errorf("%w: %s", ErrSessionNotFound, sessionName)
+	}
+	return session, windowName, nil
+}
+
+func (r *Recorder) resolveWindow(target string) (*RecordedSession, *RecordedWindow, error) {
+	session, windowName, err := r.resolve(target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if windowName == "" {
+		windowName = session.ActiveWindow
+	}
+
+	window := session.Window(windowName)
+	if window == nil {
+		return nil, nil, fmt.Errorf("can't find window: %s", windowName)
+	}
+	return session, window, nil
+}
@@ -24,10 +24,34 @@ func WithTarget(target string) OptFunc {
 	return WithKeyValue("-t", target)
 }
 
+func WithClient(name string) OptFunc {
+	return WithKeyValue("-c", name)
+}
+
 func WithDetached() OptFunc {
 	return WithFlag("-d")
 }
 
+func KillSession(opts ...OptFunc) error {
+	return Exec("kill-session", opts...)
+}
+
 func SwitchClient(opts ...OptFunc) error {
 	return Exec("switch-client", opts...)
 }
+
+func AttachSession(opts ...OptFunc) error {
+	return Exec("attach-session", append(opts, WithInteractive())...)
+}
+
+func RenameSession(opts ...OptFunc) error {
+	return Exec("rename-session", opts...)
+}
+
+func ListClients(opts ...OptFunc) ([]string, error) {
+	return OutputLines("list-clients", opts...)
+}
+
+func DetachClient(opts ...OptFunc) error {
+	return Exec("detach-client", opts...)
+}
@@ -2,7 +2,12 @@ package tmux
 
 import (
 	"fmt"
+	"os"
 	"slices"
+	"strings"
+
+	"github.com/niedch/mux-session/internal/logger"
+	"golang.org/x/term"
 )
 
 func NewTmux(socket ...string) (*Tmux, error) {
@@ -13,11 +18,105 @@ func NewTmux(socket ...string) (*Tmux, error) {
 	return t, nil
 }
 
+// NewControlTmux creates a Tmux that sends its commands over a single control
+// mode connection. It falls back to spawning a tmux process per command when
+// control mode is not available, e.g. because no server is running yet.
+func NewControlTmux(socket ...string) (*Tmux, error) {
+	t, err := NewTmux(socket...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the calling client before the control client attaches, as tmux
+	// would otherwise pick the control client as the current client
+	if InsideTmux() {
+		opts := append(t.commandOpts(), WithPrint(), WithFormat("#{client_name}"))
+		if client, err := DisplayMessage(opts...); err == nil {
+			t.client = client
+		}
+	}
+
+	control, err := NewControlClient(t.socket)
+	if err != nil {
+		logger.Printf("Control mode not available, falling back to exec: %v\n", err)
+		return t, nil
+	}
+	t.control = control
+
+	if err := t.registerControlClient(); err != nil {
+		logger.Printf("Failed to register the control client, hooks may run when it detaches: %v\n", err)
+	}
+
+	return t, nil
+}
+
+// controlClientsOption is the server option listing the names of the control
+// clients of mux-session, which hooks skip
+const controlClientsOption = "@mux-session-clients"
+
+// registerControlClient adds the control client to the control clients of
+// mux-session and drops the ones which are gone. A client-detached hook runs
+// after the client is gone, with none of its formats but its name, so it is
+// told apart by the name while it is still connected.
+func (t *Tmux) registerControlClient() error {
+	self, err := DisplayMessage(append(t.commandOpts(), WithPrint(), WithFormat("#{?client_control_mode,#{client_name},}"))...)
+	if err != nil {
+		return err
+	}
+	live, err := ListClients(append(t.commandOpts(), WithFormat("#{client_name}"))...)
+	if err != nil {
+		return err
+	}
+	registered, err := ShowOptions(append(t.commandOpts(), WithGlobal(), WithFlag("-qv"), WithArg(controlClientsOption))...)
+	if err != nil {
+		return err
+	}
+
+	return SetOption(append(t.commandOpts(), WithGlobal(), WithArgs(controlClientsOption, controlClients(registered, live, self)))...)
+}
+
+// controlClients returns the registered clients which are still live and
+// self, padded by spaces for hookCommand to match a name
+func controlClients(registered string, live []string, self string) string {
+	var clients []string
+	for name := range strings.FieldsSeq(registered) {
+		if slices.Contains(live, name) && name != self {
+			clients = append(clients, name)
+		}
+	}
+	if self != "" {
+		clients = append(clients, self)
+	}
+	return " " + strings.Join(clients, " ") + " "
+}
+
 type Tmux struct {
-	socket string
+	socket  string
+	client  string
+	control *ControlClient
+}
+
+// Close closes the control mode connection if one is open
+func (t *Tmux) Close() error {
+	if t.control == nil {
+		return nil
+	}
+	err := t.control.Close()
+	t.control = nil
+	return err
 }
 
 func (t *Tmux) commandOpts() []OptFunc {
+	opts := t.execOpts()
+	if t.control != nil {
+		opts = append(opts, WithRunner(t.control))
+	}
+	return opts
+}
+
+// execOpts are used for commands which act on the current client and must
+// not be sent by the control client
+func (t *Tmux) execOpts() []OptFunc {
 	if t.socket != "" {
 		return []OptFunc{WithSocket(t.socket)}
 	}
@@ -29,12 +128,56 @@ func (t *Tmux) ListSessions() ([]string, error) {
 	return ListSessions(opts...)
 }
 
+func (t *Tmux) SessionPath(sessionName string) (string, error) {
+	opts := append(t.commandOpts(), WithTarget("="+sessionName+":"), WithPrint(), WithFormat("#{session_path}"))
+	path, err := DisplayMessage(opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the path of session %s: %w", sessionName, err)
+	}
+	return path, nil
+}
+
+func (t *Tmux) ListWindows(sessionName string) ([]Window, error) {
+	opts := append(t.commandOpts(), WithTarget("="+sessionName), WithFormat("#{window_active}:#W"))
+	lines, err := ListWindows(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list windows of session %s: %w", sessionName, err)
+	}
+
+	windows := make([]Window, 0, len(lines))
+	for _, line := range lines {
+		active, name, _ := strings.Cut(line, ":")
+		windows = append(windows, Window{Name: name, Active: active == "1"})
+	}
+	return windows, nil
+}
+
 func (t *Tmux) CurrentSession() (string, error) {
-	opts := append(t.commandOpts(), WithPrint(), WithFormat("#S"))
+	opts := append(t.execOpts(), WithPrint(), WithFormat("#S"))
+	if t.client != "" {
+		opts = append(opts, WithClient(t.client))
+	}
 	return DisplayMessage(opts...)
 }
 
+// SessionName returns the name tmux gives a session created as name. The
+// characters which separate the parts of a target are replaced.
+func SessionName(name string) string {
+	return strings.NewReplacer(".", "_", ":", "_").Replace(name)
+}
+
+// InsideTmux reports whether mux-session runs inside a tmux client
+func InsideTmux() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// SwitchSession switches the current client to the session. Outside of tmux
+// there is no client to switch, so the terminal is attached instead.
 func (t *Tmux) SwitchSession(sessionName string) error {
+	if !InsideTmux() {
+		return t.AttachSession(sessionName)
+	}
+
 	currentSession, err := t.CurrentSession()
 	if err != nil {
 		return err
@@ -50,11 +193,44 @@ func (t *Tmux) SwitchSession(sessionName string) error {
 	}
 
 	if slices.Contains(sessions, sessionName) {
-		opts := append(t.commandOpts(), WithTarget(sessionName))
+		opts := append(t.execOpts(), WithTarget(sessionName))
+		if t.client != "" {
+			opts = append(opts, WithClient(t.client))
+		}
 		return SwitchClient(opts...)
 	}
 
-	return fmt.Errorf("session %s not found", sessionName)
+	return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionName)
+}
+
+// attachCommand is the command to attach to the session from a shell, on the
+// socket of the server
+func (t *Tmux) attachCommand(sessionName string) string {
+	if t.socket != "" {
+		return fmt.Sprintf("tmux -L %s attach -t %s", t.socket, sessionName)
+	}
+	return fmt.Sprintf("tmux attach -t %s", sessionName)
+}
+
+// AttachSession attaches the terminal to the session and blocks until the
+// client detaches. The control mode connection is closed first, as it would
+// otherwise stay attached for the whole tmux session. Without a terminal the
+// session is left detached and ErrNoTerminal is returned.
+func (t *Tmux) AttachSession(sessionName string) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("%w, session %s is left detached, attach to it with '%s'", ErrNoTerminal, sessionName, t.attachCommand(sessionName))
+	}
+
+	if err := t.Close(); err != nil {
+		logger.Printf("Failed to close control mode connection: %v\n", err)
+	}
+
+	logger.Printf("Attaching to session %s\n", sessionName)
+	opts := append(t.execOpts(), WithTarget(sessionName))
+	if err := AttachSession(opts...); err != nil {
+		return fmt.Errorf("failed to attach to session %s: %w", sessionName, err)
+	}
+	return nil
 }
 
 func (t *Tmux) NewSession(sessionName string, firstWindowName string, workingDir string, env map[string]string) error {
@@ -76,6 +252,60 @@ func (t *Tmux) NewSession(sessionName string, firstWindowName string, workingDir
 	return nil
 }
 
+func (t *Tmux) KillSession(sessionName string) error {
+	// Prefix the name with = so tmux does not fall back to a prefix match
+	opts := append(t.commandOpts(), WithTarget("="+sessionName))
+	if err := KillSession(opts...); err != nil {
+		return fmt.Errorf("failed to kill session %s: %w", sessionName, err)
+	}
+	return nil
+}
+
+func (t *Tmux) RenameSession(sessionName string, newName string) error {
+	opts := append(t.commandOpts(), WithTarget("="+sessionName), WithArg(newName))
+	if err := RenameSession(opts...); err != nil {
+		return fmt.Errorf("failed to rename session %s to %s: %w", sessionName, newName, err)
+	}
+	return nil
+}
+
+func (t *Tmux) DetachOtherClients(sessionName string) error {
+	opts := append(t.commandOpts(), WithTarget("="+sessionName), WithFormat("#{client_control_mode}:#{client_name}"))
+	clients, err := ListClients(opts...)
+	if err != nil {
+		return fmt.Errorf("failed to list clients of session %s: %w", sessionName, err)
+	}
+
+	current := t.currentClient()
+	for _, client := range clients {
+		controlMode, name, _ := strings.Cut(client, ":")
+		// Control clients, like the one of mux-session itself, are not users
+		if controlMode == "1" || name == current {
+			continue
+		}
+
+		opts := append(t.commandOpts(), WithTarget(name))
+		if err := DetachClient(opts...); err != nil {
+			return fmt.Errorf("failed to detach client %s from session %s: %w", name, sessionName, err)
+		}
+	}
+	return nil
+}
+
+// currentClient is the name of the client mux-session runs in, empty outside
+// of tmux
+func (t *Tmux) currentClient() string {
+	if t.client != "" || !InsideTmux() {
+		return t.client
+	}
+
+	opts := append(t.execOpts(), WithPrint(), WithFormat("#{client_name}"))
+	if client, err := DisplayMessage(opts...); err == nil {
+		t.client = client
+	}
+	return t.client
+}
+
 func (t *Tmux) CreateWindow(target string, windowName string, workingDir string, env map[string]string) error {
 	opts := append(t.commandOpts(),
 		WithTarget(target),
@@ -88,7 +318,7 @@ func (t *Tmux) CreateWindow(target string, windowName string, workingDir string,
 	}
 
 	if err := NewWindow(opts...); err != nil {
-		return fmt.Errorf("failed to create window %s in session %s: %w", windowName, t.socket, err)
+		return fmt.Errorf("failed to create window %s in session %s: %w", windowName, target, err)
 	}
 
 	return nil
@@ -101,7 +331,7 @@ func (t *Tmux) SendKeys(target string, cmd string) error {
 		WithEnter(),
 	)
 	if err := SendKeys(opts...); err != nil {
-		return fmt.Errorf("failed to send command to session %s: %w", t.socket, err)
+		return fmt.Errorf("failed to send command to %s: %w", target, err)
 	}
 	return nil
 }
@@ -152,3 +382,39 @@ func (t *Tmux) FocusWindow(target string) error {
 
 	return nil
 }
+
+func (t *Tmux) SetHook(sessionName string, hook string, shellCommand string) error {
+	// set-hook resolves its target as a pane, which needs the trailing colon
+	// for an exact session match
+	opts := append(t.commandOpts(), WithTarget("="+sessionName+":"), WithArgs(hook, hookCommand(shellCommand)))
+	if err := SetHook(opts...); err != nil {
+		return fmt.Errorf("failed to set hook %s for session %s: %w", hook, sessionName, err)
+	}
+	return nil
+}
+
+// hookCommand returns the tmux command which runs shellCommand for a hook.
+// The control clients of mux-session, as registered by registerControlClient,
+// are skipped, so closing the control client does not run a client-detached
+// hook.
+func hookCommand(shellCommand string) string {
+	// run-shell expands formats, so a literal # has to be doubled
+	runShell := "run-shell -b " + quoteArg(strings.ReplaceAll(shellCommand, "#", "##"))
+	skip := "#{m:* #{hook_client} *,#{" + controlClientsOption + "}}"
+	return "if-shell -F " + quoteArg(skip) + " '' " + quoteArg(runShell)
+}
+
+func (t *Tmux) DisplayMessage(message string) error {
+	// Sent without the control client, which would display it to itself
+	opts := t.execOpts()
+	if t.client != "" {
+		opts = append(opts, WithClient(t.client))
+	}
+	// The message is expanded as a format as well
+	opts = append(opts, WithArg(strings.ReplaceAll(message, "#", "##")))
+
+	if _, err := DisplayMessage(opts...); err != nil {
+		return fmt.Errorf("failed to display message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package tmux
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachCommand(t *testing.T) {
+	assert.Equal(t, "tmux attach -t api", (&Tmux{}).attachCommand("api"))
+	assert.Equal(t, "tmux -L e2e attach -t api", (&Tmux{socket: "e2e"}).attachCommand("api"))
+}
+
+func TestHookCommand(t *testing.T) {
+	got := hookCommand(`cd '/tmp/a#b' && sh -c "echo $HOME"`)
+
+	assert.Equal(t, `if-shell -F "#{m:* #{hook_client} *,#{@mux-session-clients}}" '' "run-shell -b \"cd '/tmp/a##b' && sh -c \\\"echo \\\$HOME\\\"\""`, got)
+}
+
+func TestControlClients(t *testing.T) {
+	live := []string{"/dev/pts/1", "client-20", "client-30"}
+
+	assert.Equal(t, " client-30 ", controlClients("", live, "client-30"))
+	assert.Equal(t, " client-20 client-30 ", controlClients(" client-10 client-20 ", live, "client-30"), "gone clients are dropped")
+	assert.Equal(t, " client-20 client-30 ", controlClients(" client-20 client-30 ", live, "client-30"))
+}
+
+func TestSessionName(t *testing.T) {
+	assert.Equal(t, "api", SessionName("api"))
+	assert.Equal(t, "org/api_v2_x", SessionName("org/api.v2:x"))
+}
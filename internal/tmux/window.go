@@ -4,6 +4,10 @@ func NewWindow(opts ...OptFunc) error {
 	return Exec("new-window", opts...)
 }
 
+func ListWindows(opts ...OptFunc) ([]string, error) {
+	return OutputLines("list-windows", opts...)
+}
+
 func SelectWindow(opts ...OptFunc) error {
 	return Exec("select-window", opts...)
 }
@@ -33,6 +33,12 @@ func GeneratePrefix(level int, isLast bool) string {
 }
 
 func FlattenItems(items []dataproviders.Item) []dataproviders.Item {
+	return FlattenItemsCollapsed(items, nil)
+}
+
+// FlattenItemsCollapsed flattens items like FlattenItems, but leaves out the
+// subitems of every item for which collapsed returns true
+func FlattenItemsCollapsed(items []dataproviders.Item, collapsed func(item dataproviders.Item) bool) []dataproviders.Item {
 	var result []dataproviders.Item
 
 	for i, item := range items {
@@ -45,7 +51,7 @@ func FlattenItems(items []dataproviders.Item) []dataproviders.Item {
 
 		result = append(result, item)
 
-		if len(item.SubItems) > 0 {
+		if len(item.SubItems) > 0 && (collapsed == nil || !collapsed(item)) {
 			subItems := make([]dataproviders.Item, len(item.SubItems))
 			copy(subItems, item.SubItems)
 
@@ -53,7 +59,7 @@ func FlattenItems(items []dataproviders.Item) []dataproviders.Item {
 				subItems[j].TreeLevel = item.TreeLevel + 1
 			}
 
-			result = append(result, FlattenItems(subItems)...)
+			result = append(result, FlattenItemsCollapsed(subItems, collapsed)...)
 		}
 	}
 